@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/uncomfyhalomacro/chirpy/internal/auth"
+	"github.com/uncomfyhalomacro/chirpy/internal/database"
+	"log"
+	"net/http"
+	"os"
+	"time"
+)
+
+const accountDeletionGrace = 30 * 24 * time.Hour
+
+func userToJSON(user database.User) returnUser {
+	respBody := returnUser{
+		ID:          user.ID,
+		CreatedAt:   user.CreatedAt,
+		UpdatedAt:   user.UpdatedAt,
+		Email:       user.Email,
+		IsChirpyRed: user.IsChirpyRed,
+		Handle:      user.Handle.String,
+	}
+	if user.DeleteAfter.Valid {
+		respBody.DeleteAfter = &user.DeleteAfter.Time
+	}
+	return respBody
+}
+
+func writeUser(w http.ResponseWriter, user database.User) {
+	dat, errMarshal := json.Marshal(userToJSON(user))
+	if errMarshal != nil {
+		msg := fmt.Sprintf("500 - %s", errMarshal)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+	w.Write(dat)
+}
+
+// deleteAccount schedules the caller's account for deletion once they
+// confirm their password. Every session is signed out straight away; the
+// account itself is only removed after accountDeletionGrace so it can be
+// restored until then.
+func (cfg *apiConfig) deleteAccount(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Println("no bearer")
+		w.WriteHeader(401)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+	userID, err := cfg.validateToken(r.Context(), token)
+	if err != nil {
+		log.Printf("jwt error: %v", err)
+		w.WriteHeader(401)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+	var postData struct {
+		Password string `json:"password"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	err = decoder.Decode(&postData)
+	if err != nil {
+		msg := fmt.Sprintf("400 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 400)
+		return
+	}
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		log.Printf("%v\n", err)
+		http.Error(w, "Unauthorized", 401)
+		return
+	}
+	if err = auth.CheckPasswordHash(postData.Password, user.HashedPassword); err != nil {
+		http.Error(w, "403 - the password does not match", 403)
+		return
+	}
+	tx, err := cfg.conn.BeginTx(r.Context(), nil)
+	if err != nil {
+		msg := fmt.Sprintf("500 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+	now := time.Now()
+	user, err = qtx.ScheduleUserDeletion(r.Context(), database.ScheduleUserDeletionParams{
+		ID:          userID,
+		DeleteAfter: sql.NullTime{Time: now.Add(accountDeletionGrace), Valid: true},
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "409 - this account is already scheduled for deletion", 409)
+		return
+	}
+	if err == nil {
+		err = qtx.RevokeUserTokens(r.Context(), database.RevokeUserTokensParams{
+			UserID:    userID,
+			RevokedAt: sql.NullTime{Time: now, Valid: true},
+		})
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		msg := fmt.Sprintf("500 - %s", err)
+		log.Printf("failed to schedule account deletion! %s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	writeUser(w, user)
+}
+
+// restoreAccount cancels a pending deletion. Logging in is refused while
+// the deletion is pending, so it takes the same email and password as
+// loginUser rather than a token.
+func (cfg *apiConfig) restoreAccount(w http.ResponseWriter, r *http.Request) {
+	var postData UserLoginDetail
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&postData)
+	if err != nil {
+		msg := fmt.Sprintf("400 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 400)
+		return
+	}
+	user, err := cfg.db.GetUser(r.Context(), postData.Email)
+	if err != nil {
+		log.Printf("%v\n", err)
+		http.Error(w, "Unauthorized", 401)
+		return
+	}
+	if err = auth.CheckPasswordHash(postData.Password, user.HashedPassword); err != nil {
+		log.Printf("%v\n", err)
+		http.Error(w, "Unauthorized", 401)
+		return
+	}
+	if !user.DeleteAfter.Valid {
+		http.Error(w, "409 - this account is not scheduled for deletion", 409)
+		return
+	}
+	user, err = cfg.db.CancelUserDeletion(r.Context(), database.CancelUserDeletionParams{
+		ID:  user.ID,
+		Now: sql.NullTime{Time: time.Now(), Valid: true},
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "410 - the grace period of this account has passed", 410)
+		return
+	}
+	if err != nil {
+		msg := fmt.Sprintf("500 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	writeUser(w, user)
+}
+
+// runAccountDeletions runs until ctx is done, deleting accounts whose
+// grace period has passed.
+func (cfg *apiConfig) runAccountDeletions(ctx context.Context) {
+	ticker := time.NewTicker(purgeInterval)
+	defer ticker.Stop()
+	for {
+		if err := cfg.deleteDueAccounts(ctx); err != nil {
+			log.Printf("failed to delete accounts: %v\n", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (cfg *apiConfig) deleteDueAccounts(ctx context.Context) error {
+	for {
+		users, err := cfg.db.ListDueUserDeletions(ctx, database.ListDueUserDeletionsParams{
+			DueBy:     sql.NullTime{Time: time.Now(), Valid: true},
+			PageLimit: purgeBatchSize,
+		})
+		if err != nil {
+			return err
+		}
+		for _, user := range users {
+			if err = cfg.deleteAccountNow(ctx, user); err != nil {
+				return err
+			}
+		}
+		if len(users) < purgeBatchSize {
+			return nil
+		}
+	}
+}
+
+// deleteAccountNow removes a user for good. Chirps that have replies
+// become tombstones without an author, as the purger would do, so
+// those threads keep their shape. The foreign keys take the other rows
+// with them; the files on disk are looked up first and removed once the
+// rows are gone.
+func (cfg *apiConfig) deleteAccountNow(ctx context.Context, user database.User) error {
+	replied, err := cfg.db.ListRepliedUserChirps(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	for _, chirp := range replied {
+		if err = cfg.tombstoneChirp(ctx, chirp.ID); err != nil {
+			return err
+		}
+	}
+	if err = cfg.db.DisownTombstones(ctx, user.ID); err != nil {
+		return err
+	}
+	attachments, err := cfg.db.ListUserAttachments(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	jobs, err := cfg.db.ListExportJobs(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	if err = cfg.db.DeleteUser(ctx, user.ID); err != nil {
+		return err
+	}
+	cfg.removeAttachmentFiles(attachments)
+	for _, job := range jobs {
+		if err := os.Remove(cfg.exportPath(job.ID)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Printf("failed to remove export %s: %v\n", job.ID, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/uncomfyhalomacro/chirpy/internal/database"
+	"github.com/uncomfyhalomacro/chirpy/internal/media"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// mediaNameRe matches the only file names the media route will serve.
+var mediaNameRe = regexp.MustCompile(`^[0-9a-f-]{36}(_thumb)?\.(jpg|png)$`)
+
+type returnAttachment struct {
+	ID           uuid.UUID `json:"id"`
+	URL          string    `json:"url"`
+	ThumbnailURL string    `json:"thumbnail_url"`
+	ContentType  string    `json:"content_type"`
+	Width        int32     `json:"width"`
+	Height       int32     `json:"height"`
+}
+
+func attachmentToJSON(attachment database.ChirpAttachment) returnAttachment {
+	return returnAttachment{
+		ID:           attachment.ID,
+		URL:          "/media/" + attachmentFileName(attachment, false),
+		ThumbnailURL: "/media/" + attachmentFileName(attachment, true),
+		ContentType:  attachment.ContentType,
+		Width:        attachment.Width,
+		Height:       attachment.Height,
+	}
+}
+
+func attachmentFileName(attachment database.ChirpAttachment, thumbnail bool) string {
+	name := attachment.ID.String()
+	if thumbnail {
+		name += "_thumb"
+	}
+	return name + media.Extension(attachment.ContentType)
+}
+
+// writeAttachmentFiles stores an attachment's image and thumbnail in the
+// media directory. Nothing is left behind if either write fails.
+func (cfg *apiConfig) writeAttachmentFiles(attachment database.ChirpAttachment, img media.Image) error {
+	path := filepath.Join(cfg.mediaDir, attachmentFileName(attachment, false))
+	if err := os.WriteFile(path, img.Data, 0o644); err != nil {
+		return err
+	}
+	thumbPath := filepath.Join(cfg.mediaDir, attachmentFileName(attachment, true))
+	if err := os.WriteFile(thumbPath, img.Thumbnail, 0o644); err != nil {
+		os.Remove(path)
+		return err
+	}
+	return nil
+}
+
+func (cfg *apiConfig) removeAttachmentFiles(attachments []database.ChirpAttachment) {
+	for _, attachment := range attachments {
+		for _, thumbnail := range []bool{false, true} {
+			path := filepath.Join(cfg.mediaDir, attachmentFileName(attachment, thumbnail))
+			if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				log.Printf("failed to remove %s: %v\n", path, err)
+			}
+		}
+	}
+}
+
+// isMultipart reports whether a chirp is being posted as a form with
+// images rather than as JSON.
+func isMultipart(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && mediaType == "multipart/form-data"
+}
+
+// parseChirpForm reads a multipart chirp: the `body`, `reply_to` and
+// `publish_at` fields plus up to maxImages files under `images`.
+func parseChirpForm(w http.ResponseWriter, r *http.Request, maxImages int) (postDataShape, []media.Image, error) {
+	var postData postDataShape
+	// leave some room for the text fields and the multipart framing
+	r.Body = http.MaxBytesReader(w, r.Body, int64(maxImages)*media.MaxImageSize+1<<20)
+	if err := r.ParseMultipartForm(1 << 20); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return postData, nil, media.ErrTooLarge
+		}
+		return postData, nil, err
+	}
+	postData.Body = r.FormValue("body")
+	postData.Visibility = r.FormValue("visibility")
+	postData.ContentWarning = r.FormValue("content_warning")
+	if sensitive := r.FormValue("sensitive"); sensitive != "" {
+		flag, err := strconv.ParseBool(sensitive)
+		if err != nil {
+			return postData, nil, err
+		}
+		postData.Sensitive = flag
+	}
+	if replyTo := r.FormValue("reply_to"); replyTo != "" {
+		id, err := uuid.Parse(replyTo)
+		if err != nil {
+			return postData, nil, err
+		}
+		postData.ReplyTo = &id
+	}
+	if publishAt := r.FormValue("publish_at"); publishAt != "" {
+		t, err := time.Parse(time.RFC3339, publishAt)
+		if err != nil {
+			return postData, nil, err
+		}
+		postData.PublishAt = &t
+	}
+	if options := r.MultipartForm.Value["poll_options"]; len(options) > 0 {
+		postData.Poll = &pollShape{Options: options}
+		if closesAt := r.FormValue("poll_closes_at"); closesAt != "" {
+			t, err := time.Parse(time.RFC3339, closesAt)
+			if err != nil {
+				return postData, nil, err
+			}
+			postData.Poll.ClosesAt = t
+		}
+	}
+	files := r.MultipartForm.File["images"]
+	if len(files) > maxImages {
+		return postData, nil, fmt.Errorf("a chirp can have at most %d images", maxImages)
+	}
+	images := make([]media.Image, 0, len(files))
+	for _, header := range files {
+		if header.Size > media.MaxImageSize {
+			return postData, nil, media.ErrTooLarge
+		}
+		file, err := header.Open()
+		if err != nil {
+			return postData, nil, err
+		}
+		data, err := io.ReadAll(io.LimitReader(file, media.MaxImageSize+1))
+		file.Close()
+		if err != nil {
+			return postData, nil, err
+		}
+		img, err := media.Process(data)
+		if err != nil {
+			return postData, nil, err
+		}
+		images = append(images, img)
+	}
+	return postData, images, nil
+}
+
+func attachmentErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, media.ErrTooLarge):
+		return 413
+	case errors.Is(err, media.ErrUnsupportedType):
+		return 415
+	}
+	return 400
+}
+
+func (cfg *apiConfig) serveMedia(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if !mediaNameRe.MatchString(name) {
+		http.Error(w, http.StatusText(404), 404)
+		return
+	}
+	id, err := uuid.Parse(name[:36])
+	if err != nil {
+		http.Error(w, http.StatusText(404), 404)
+		return
+	}
+	attachment, err := cfg.db.GetChirpAttachment(r.Context(), id)
+	if err != nil {
+		http.Error(w, http.StatusText(404), 404)
+		return
+	}
+	chirp, ok := cfg.attachmentChirp(r, attachment)
+	if !ok {
+		http.Error(w, http.StatusText(404), 404)
+		return
+	}
+	// file names are random and never reused, so the content never
+	// changes; shared caches only get to keep what anyone may see
+	if chirp.Visibility == visibilityPublic && !chirp.PublishAt.Valid {
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	} else {
+		w.Header().Set("Cache-Control", "private, max-age=31536000, immutable")
+	}
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	http.ServeFile(w, r, filepath.Join(cfg.mediaDir, name))
+}
+
+// attachmentChirp finds the chirp an attachment belongs to when the
+// caller may see it. Authors can also see the media of their own
+// scheduled chirps.
+func (cfg *apiConfig) attachmentChirp(r *http.Request, attachment database.ChirpAttachment) (database.Chirp, bool) {
+	viewerID := cfg.viewerID(r)
+	chirp, err := cfg.db.GetChirp(r.Context(), database.GetChirpParams{
+		ID:       attachment.ChirpID,
+		ViewerID: viewerID,
+	})
+	if err == nil {
+		return chirp, true
+	}
+	chirp, err = cfg.db.GetScheduledChirp(r.Context(), attachment.ChirpID)
+	if err != nil || !viewerID.Valid || chirp.UserID.UUID != viewerID.UUID {
+		return chirp, false
+	}
+	return chirp, true
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/uncomfyhalomacro/chirpy/internal/auth"
+	"github.com/uncomfyhalomacro/chirpy/internal/database"
+	"github.com/uncomfyhalomacro/chirpy/internal/pagination"
+	"log"
+	"net/http"
+	"time"
+)
+
+func (cfg *apiConfig) bookmarkChirp(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Println("no bearer")
+		w.WriteHeader(401)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+	userID, err := cfg.validateToken(r.Context(), token)
+	if err != nil {
+		log.Printf("jwt error: %v", err)
+		w.WriteHeader(401)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+	id, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		msg := fmt.Sprintf("400 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 400)
+		return
+	}
+	chirp, err := cfg.db.GetChirp(r.Context(), database.GetChirpParams{
+		ID:       id,
+		ViewerID: uuid.NullUUID{UUID: userID, Valid: true},
+	})
+	if err != nil {
+		msg := fmt.Sprintf("404 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 404)
+		return
+	}
+	err = cfg.db.AddBookmark(r.Context(), database.AddBookmarkParams{
+		UserID:    userID,
+		ChirpID:   chirp.ID,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		http.Error(w, http.StatusText(500), 500)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) unbookmarkChirp(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Println("no bearer")
+		w.WriteHeader(401)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+	userID, err := cfg.validateToken(r.Context(), token)
+	if err != nil {
+		log.Printf("jwt error: %v", err)
+		w.WriteHeader(401)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+	id, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		msg := fmt.Sprintf("400 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 400)
+		return
+	}
+	err = cfg.db.RemoveBookmark(r.Context(), database.RemoveBookmarkParams{
+		UserID:  userID,
+		ChirpID: id,
+	})
+	if err != nil {
+		http.Error(w, http.StatusText(500), 500)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+// getBookmarks lists the caller's bookmarks. Bookmarks are private, so
+// there is no way to list anyone else's.
+func (cfg *apiConfig) getBookmarks(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Println("no bearer")
+		w.WriteHeader(401)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+	userID, err := cfg.validateToken(r.Context(), token)
+	if err != nil {
+		log.Printf("jwt error: %v", err)
+		w.WriteHeader(401)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+	limit, err := pagination.ParseLimit(r.URL.Query().Get("limit"))
+	if err != nil {
+		msg := fmt.Sprintf("400 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 400)
+		return
+	}
+	params := database.ListBookmarksParams{
+		UserID:    userID,
+		PageLimit: limit + 1,
+	}
+	if rawCursor := r.URL.Query().Get("cursor"); rawCursor != "" {
+		cursor, err := pagination.DecodeCursor(rawCursor)
+		if err != nil {
+			msg := fmt.Sprintf("400 - %s", err)
+			log.Printf("%s\n", msg)
+			http.Error(w, msg, 400)
+			return
+		}
+		params.AfterBookmarkedAt = sql.NullTime{Time: cursor.Time, Valid: true}
+		params.AfterID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
+	}
+	rows, err := cfg.db.ListBookmarks(r.Context(), params)
+	if err != nil {
+		msg := fmt.Sprintf("500 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	var page chirpsPage
+	if len(rows) > int(limit) {
+		rows = rows[:limit]
+		last := rows[len(rows)-1]
+		page.NextCursor = pagination.Cursor{Time: last.BookmarkedAt, ID: last.Chirp.ID}.Encode()
+	}
+	chirps := make([]database.Chirp, 0, len(rows))
+	for _, row := range rows {
+		chirps = append(chirps, row.Chirp)
+	}
+	page.Chirps, err = cfg.loadChirpsJSON(r.Context(), uuid.NullUUID{UUID: userID, Valid: true}, chirps)
+	if err != nil {
+		msg := fmt.Sprintf("500 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+
+	dat, errMarshal := json.Marshal(page)
+	if errMarshal != nil {
+		msg := fmt.Sprintf("500 - %s", errMarshal)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+	w.Write(dat)
+}
@@ -0,0 +1,389 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/uncomfyhalomacro/chirpy/internal/auth"
+	"github.com/uncomfyhalomacro/chirpy/internal/database"
+	"github.com/uncomfyhalomacro/chirpy/internal/pagination"
+	"log"
+	"net/http"
+	"time"
+)
+
+// maxDraftSize caps a draft request body. Drafts are not held to the chirp
+// length limit, this only keeps a single request from being unbounded.
+const maxDraftSize = 64 << 10
+
+type draftShape struct {
+	Body string `json:"body"`
+}
+
+type returnDraft struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Body      string    `json:"body"`
+}
+
+type draftsPage struct {
+	Drafts     []returnDraft `json:"drafts"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+}
+
+func draftToJSON(draft database.Draft) returnDraft {
+	return returnDraft{
+		ID:        draft.ID,
+		CreatedAt: draft.CreatedAt,
+		UpdatedAt: draft.UpdatedAt,
+		Body:      draft.Body,
+	}
+}
+
+func writeDraftJSON(w http.ResponseWriter, status int, draft database.Draft) {
+	dat, errMarshal := json.Marshal(draftToJSON(draft))
+	if errMarshal != nil {
+		msg := fmt.Sprintf("500 - %s", errMarshal)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(dat)
+}
+
+func decodeDraft(w http.ResponseWriter, r *http.Request) (draftShape, error) {
+	var postData draftShape
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxDraftSize))
+	err := decoder.Decode(&postData)
+	return postData, err
+}
+
+func (cfg *apiConfig) getDrafts(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Println("no bearer")
+		w.WriteHeader(401)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+	userID, err := cfg.validateToken(r.Context(), token)
+	if err != nil {
+		log.Printf("jwt error: %v", err)
+		w.WriteHeader(401)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+	limit, err := pagination.ParseLimit(r.URL.Query().Get("limit"))
+	if err != nil {
+		msg := fmt.Sprintf("400 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 400)
+		return
+	}
+	params := database.ListDraftsParams{
+		UserID:    userID,
+		PageLimit: limit + 1,
+	}
+	if rawCursor := r.URL.Query().Get("cursor"); rawCursor != "" {
+		cursor, err := pagination.DecodeCursor(rawCursor)
+		if err != nil {
+			msg := fmt.Sprintf("400 - %s", err)
+			log.Printf("%s\n", msg)
+			http.Error(w, msg, 400)
+			return
+		}
+		params.AfterUpdatedAt = sql.NullTime{Time: cursor.Time, Valid: true}
+		params.AfterID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
+	}
+	drafts, err := cfg.db.ListDrafts(r.Context(), params)
+	if err != nil {
+		msg := fmt.Sprintf("500 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	page := draftsPage{
+		Drafts: []returnDraft{},
+	}
+	if len(drafts) > int(limit) {
+		drafts = drafts[:limit]
+		last := drafts[len(drafts)-1]
+		page.NextCursor = pagination.Cursor{Time: last.UpdatedAt, ID: last.ID}.Encode()
+	}
+	for _, draft := range drafts {
+		page.Drafts = append(page.Drafts, draftToJSON(draft))
+	}
+
+	dat, errMarshal := json.Marshal(page)
+	if errMarshal != nil {
+		msg := fmt.Sprintf("500 - %s", errMarshal)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+	w.Write(dat)
+}
+
+func (cfg *apiConfig) getDraft(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Println("no bearer")
+		w.WriteHeader(401)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+	userID, err := cfg.validateToken(r.Context(), token)
+	if err != nil {
+		log.Printf("jwt error: %v", err)
+		w.WriteHeader(401)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+	id, err := uuid.Parse(r.PathValue("draftID"))
+	if err != nil {
+		msg := fmt.Sprintf("400 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 400)
+		return
+	}
+	draft, err := cfg.db.GetDraft(r.Context(), database.GetDraftParams{
+		ID:     id,
+		UserID: userID,
+	})
+	if err != nil {
+		msg := fmt.Sprintf("404 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 404)
+		return
+	}
+	writeDraftJSON(w, 200, draft)
+}
+
+func (cfg *apiConfig) postDraft(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Println("no bearer")
+		w.WriteHeader(401)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+	userID, err := cfg.validateToken(r.Context(), token)
+	if err != nil {
+		log.Printf("jwt error: %v", err)
+		w.WriteHeader(401)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+	postData, err := decodeDraft(w, r)
+	if err != nil {
+		msg := fmt.Sprintf("400 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 400)
+		return
+	}
+	now := time.Now()
+	draft, err := cfg.db.CreateDraft(r.Context(), database.CreateDraftParams{
+		CreatedAt: now,
+		UpdatedAt: now,
+		Body:      postData.Body,
+		UserID:    userID,
+	})
+	if err != nil {
+		msg := fmt.Sprintf("500 - %s", err)
+		log.Printf("failed to create draft! %s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	writeDraftJSON(w, 201, draft)
+}
+
+func (cfg *apiConfig) putDraft(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Println("no bearer")
+		w.WriteHeader(401)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+	userID, err := cfg.validateToken(r.Context(), token)
+	if err != nil {
+		log.Printf("jwt error: %v", err)
+		w.WriteHeader(401)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+	id, err := uuid.Parse(r.PathValue("draftID"))
+	if err != nil {
+		msg := fmt.Sprintf("400 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 400)
+		return
+	}
+	postData, err := decodeDraft(w, r)
+	if err != nil {
+		msg := fmt.Sprintf("400 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 400)
+		return
+	}
+	draft, err := cfg.db.UpdateDraft(r.Context(), database.UpdateDraftParams{
+		ID:        id,
+		UserID:    userID,
+		Body:      postData.Body,
+		UpdatedAt: time.Now(),
+	})
+	if err == sql.ErrNoRows {
+		http.Error(w, http.StatusText(404), 404)
+		return
+	}
+	if err != nil {
+		msg := fmt.Sprintf("500 - %s", err)
+		log.Printf("failed to update draft! %s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	writeDraftJSON(w, 200, draft)
+}
+
+func (cfg *apiConfig) deleteDraft(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Println("no bearer")
+		w.WriteHeader(401)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+	userID, err := cfg.validateToken(r.Context(), token)
+	if err != nil {
+		log.Printf("jwt error: %v", err)
+		w.WriteHeader(401)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+	id, err := uuid.Parse(r.PathValue("draftID"))
+	if err != nil {
+		msg := fmt.Sprintf("400 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 400)
+		return
+	}
+	deleted, err := cfg.db.DeleteDraft(r.Context(), database.DeleteDraftParams{
+		ID:     id,
+		UserID: userID,
+	})
+	if err != nil {
+		http.Error(w, http.StatusText(500), 500)
+		return
+	}
+	if deleted == 0 {
+		http.Error(w, http.StatusText(404), 404)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+// publishDraft turns a draft into a chirp. The draft is deleted and the
+// chirp created in one transaction, and the delete locks the draft's row,
+// so of two concurrent publishes only the first finds a draft to publish.
+func (cfg *apiConfig) publishDraft(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Println("no bearer")
+		w.WriteHeader(401)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+	userID, err := cfg.validateToken(r.Context(), token)
+	if err != nil {
+		log.Printf("jwt error: %v", err)
+		w.WriteHeader(401)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+	id, err := uuid.Parse(r.PathValue("draftID"))
+	if err != nil {
+		msg := fmt.Sprintf("400 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 400)
+		return
+	}
+	perks, ok := cfg.callerEntitlements(w, r, userID)
+	if !ok {
+		return
+	}
+	if !cfg.limiter.Allow(userID, perks.ChirpsPerMinute) {
+		http.Error(w, "429 - you are posting chirps too quickly", 429)
+		return
+	}
+	tx, err := cfg.conn.BeginTx(r.Context(), nil)
+	if err != nil {
+		msg := fmt.Sprintf("500 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+	draft, err := qtx.TakeDraft(r.Context(), database.TakeDraftParams{
+		ID:     id,
+		UserID: userID,
+	})
+	if err == sql.ErrNoRows {
+		http.Error(w, http.StatusText(404), 404)
+		return
+	}
+	if err != nil {
+		msg := fmt.Sprintf("500 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	// a rejected body rolls the delete back, so the draft stays around to
+	// be fixed
+	cleanedBody, flagged, violations := cfg.cleanChirpBody(draft.Body, perks.MaxChirpLength)
+	if len(violations) > 0 {
+		writeViolations(w, violations)
+		return
+	}
+	now := time.Now()
+	chirp, err := cfg.insertChirp(r.Context(), qtx, database.CreateChirpParams{
+		Body:       cleanedBody,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+		UserID:     userID,
+		Visibility: visibilityPublic,
+		Sensitive:  flagged,
+	})
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		msg := fmt.Sprintf("500 - %s", err)
+		log.Printf("failed to publish draft! %s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	respBody, err := cfg.loadChirpJSON(r.Context(), uuid.NullUUID{UUID: userID, Valid: true}, chirp)
+	if err != nil {
+		msg := fmt.Sprintf("500 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	dat, errMarshal := json.Marshal(respBody)
+	if errMarshal != nil {
+		msg := fmt.Sprintf("500 - %s", errMarshal)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(201)
+	w.Write(dat)
+}
@@ -0,0 +1,326 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/uncomfyhalomacro/chirpy/internal/auth"
+	"github.com/uncomfyhalomacro/chirpy/internal/database"
+	"github.com/uncomfyhalomacro/chirpy/internal/entitlements"
+	"github.com/uncomfyhalomacro/chirpy/internal/export"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	exportInterval = time.Minute
+	// an archive can be downloaded for this long after it is built
+	exportRetention = 7 * 24 * time.Hour
+	// a job still running after this long is assumed to belong to a
+	// server that went away, and is picked up again
+	exportStaleAfter = 15 * time.Minute
+
+	exportPending = "pending"
+	exportRunning = "running"
+	exportReady   = "ready"
+	exportFailed  = "failed"
+)
+
+type returnExportJob struct {
+	ID         uuid.UUID  `json:"id"`
+	Status     string     `json:"status"`
+	CreatedAt  time.Time  `json:"created_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+}
+
+func exportJobToJSON(job database.ExportJob) returnExportJob {
+	respBody := returnExportJob{
+		ID:        job.ID,
+		Status:    job.Status,
+		CreatedAt: job.CreatedAt,
+	}
+	if job.FinishedAt.Valid {
+		respBody.FinishedAt = &job.FinishedAt.Time
+	}
+	if job.ExpiresAt.Valid {
+		respBody.ExpiresAt = &job.ExpiresAt.Time
+	}
+	return respBody
+}
+
+func (cfg *apiConfig) exportPath(jobID uuid.UUID) string {
+	return filepath.Join(cfg.exportDir, jobID.String()+".zip")
+}
+
+// runExports builds requested archives until ctx is done. Jobs live in the
+// database, so requests made while the server was down are built on the
+// first pass. postExport wakes it up so nobody waits for the next tick.
+func (cfg *apiConfig) runExports(ctx context.Context) {
+	ticker := time.NewTicker(exportInterval)
+	defer ticker.Stop()
+	for {
+		if err := cfg.buildPendingExports(ctx); err != nil {
+			log.Printf("failed to build exports: %v\n", err)
+		}
+		if err := cfg.purgeExpiredExports(ctx); err != nil {
+			log.Printf("failed to purge expired exports: %v\n", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-cfg.exportWake:
+		}
+	}
+}
+
+func (cfg *apiConfig) buildPendingExports(ctx context.Context) error {
+	for {
+		now := time.Now()
+		job, err := cfg.db.ClaimExportJob(ctx, database.ClaimExportJobParams{
+			StartedAt:   sql.NullTime{Time: now, Valid: true},
+			StaleBefore: sql.NullTime{Time: now.Add(-exportStaleAfter), Valid: true},
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		status := exportReady
+		if err = cfg.buildExport(ctx, job); err != nil {
+			log.Printf("failed to build export %s: %v\n", job.ID, err)
+			status = exportFailed
+		}
+		finishedAt := time.Now()
+		err = cfg.db.FinishExportJob(ctx, database.FinishExportJobParams{
+			ID:         job.ID,
+			Status:     status,
+			FinishedAt: sql.NullTime{Time: finishedAt, Valid: true},
+			ExpiresAt:  sql.NullTime{Time: finishedAt.Add(exportRetention), Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// buildExport writes the archive for a job. It is written under a
+// temporary name and renamed at the end, so a download never sees half an
+// archive.
+func (cfg *apiConfig) buildExport(ctx context.Context, job database.ExportJob) error {
+	user, err := cfg.db.GetUserByID(ctx, job.UserID)
+	if err != nil {
+		return err
+	}
+	chirps, err := cfg.db.ListChirpsForExport(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	sessions, err := cfg.db.ListActiveSessions(ctx, database.ListActiveSessionsParams{
+		UserID:    user.ID,
+		ExpiresAt: now,
+	})
+	if err != nil {
+		return err
+	}
+
+	perks := entitlements.For(user.IsChirpyRed)
+	archive := export.Archive{
+		GeneratedAt: now,
+		Profile: export.Profile{
+			ID:        user.ID,
+			Email:     user.Email,
+			Handle:    user.Handle.String,
+			CreatedAt: user.CreatedAt,
+			UpdatedAt: user.UpdatedAt,
+		},
+		ChirpyRed: export.ChirpyRed{
+			Active:          user.IsChirpyRed,
+			MaxChirpLength:  perks.MaxChirpLength,
+			CanEditChirps:   perks.CanEditChirps,
+			MaxAttachments:  perks.MaxAttachments,
+			ChirpsPerMinute: perks.ChirpsPerMinute,
+		},
+		Chirps:   make([]export.Chirp, 0, len(chirps)),
+		Sessions: make([]export.Session, 0, len(sessions)),
+	}
+	for _, chirp := range chirps {
+		c := export.Chirp{
+			ID:             chirp.ID,
+			CreatedAt:      chirp.CreatedAt,
+			UpdatedAt:      chirp.UpdatedAt,
+			Body:           chirp.Body,
+			Visibility:     chirp.Visibility,
+			ContentWarning: chirp.ContentWarning.String,
+			Sensitive:      chirp.Sensitive,
+		}
+		if chirp.ParentChirpID.Valid {
+			c.ReplyTo = &chirp.ParentChirpID.UUID
+		}
+		if chirp.RechirpOfID.Valid {
+			c.RechirpOf = &chirp.RechirpOfID.UUID
+		}
+		if chirp.QuoteOfID.Valid {
+			c.QuoteOf = &chirp.QuoteOfID.UUID
+		}
+		if chirp.EditedAt.Valid {
+			c.EditedAt = &chirp.EditedAt.Time
+		}
+		if chirp.PublishAt.Valid {
+			c.PublishAt = &chirp.PublishAt.Time
+		}
+		if chirp.DeletedAt.Valid {
+			c.DeletedAt = &chirp.DeletedAt.Time
+		}
+		archive.Chirps = append(archive.Chirps, c)
+	}
+	for _, session := range sessions {
+		archive.Sessions = append(archive.Sessions, export.Session{
+			CreatedAt: session.CreatedAt,
+			ExpiresAt: session.ExpiresAt,
+		})
+	}
+
+	path := cfg.exportPath(job.ID)
+	f, err := os.CreateTemp(cfg.exportDir, job.ID.String()+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if err = export.Write(f, archive); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+func (cfg *apiConfig) purgeExpiredExports(ctx context.Context) error {
+	jobs, err := cfg.db.DeleteExpiredExportJobs(ctx, sql.NullTime{Time: time.Now(), Valid: true})
+	if err != nil {
+		return err
+	}
+	for _, job := range jobs {
+		if err := os.Remove(cfg.exportPath(job.ID)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Printf("failed to remove export %s: %v\n", job.ID, err)
+		}
+	}
+	return nil
+}
+
+func writeExportJob(w http.ResponseWriter, job database.ExportJob) {
+	dat, errMarshal := json.Marshal(exportJobToJSON(job))
+	if errMarshal != nil {
+		msg := fmt.Sprintf("500 - %s", errMarshal)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(202)
+	w.Write(dat)
+}
+
+func (cfg *apiConfig) postExport(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Println("no bearer")
+		w.WriteHeader(401)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+	userID, err := cfg.validateToken(r.Context(), token)
+	if err != nil {
+		log.Printf("jwt error: %v", err)
+		w.WriteHeader(401)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+	// asking again while an export is being built gets the same job
+	job, err := cfg.db.GetLatestExportJob(r.Context(), userID)
+	if err == nil && (job.Status == exportPending || job.Status == exportRunning) {
+		writeExportJob(w, job)
+		return
+	}
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		msg := fmt.Sprintf("500 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	job, err = cfg.db.CreateExportJob(r.Context(), database.CreateExportJobParams{
+		CreatedAt: time.Now(),
+		UserID:    userID,
+	})
+	if err != nil {
+		msg := fmt.Sprintf("500 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	select {
+	case cfg.exportWake <- struct{}{}:
+	default:
+	}
+	writeExportJob(w, job)
+}
+
+// getExport downloads the caller's latest archive once it is ready. Until
+// then it answers 202 with the job so clients can poll it.
+func (cfg *apiConfig) getExport(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Println("no bearer")
+		w.WriteHeader(401)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+	userID, err := cfg.validateToken(r.Context(), token)
+	if err != nil {
+		log.Printf("jwt error: %v", err)
+		w.WriteHeader(401)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+	job, err := cfg.db.GetLatestExportJob(r.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "404 - no export has been requested", 404)
+		return
+	}
+	if err != nil {
+		msg := fmt.Sprintf("500 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	switch job.Status {
+	case exportPending, exportRunning:
+		writeExportJob(w, job)
+		return
+	case exportFailed:
+		http.Error(w, "500 - the export failed, request a new one", 500)
+		return
+	}
+	f, err := os.Open(cfg.exportPath(job.ID))
+	if err != nil {
+		msg := fmt.Sprintf("500 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	defer f.Close()
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"chirpy-export-%s.zip\"", job.FinishedAt.Time.Format("2006-01-02")))
+	http.ServeContent(w, r, "", job.FinishedAt.Time, f)
+}
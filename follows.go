@@ -0,0 +1,261 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/uncomfyhalomacro/chirpy/internal/auth"
+	"github.com/uncomfyhalomacro/chirpy/internal/database"
+	"github.com/uncomfyhalomacro/chirpy/internal/pagination"
+	"log"
+	"net/http"
+	"time"
+)
+
+type returnFollow struct {
+	ID         uuid.UUID `json:"id"`
+	Handle     string    `json:"handle,omitempty"`
+	FollowedAt time.Time `json:"followed_at"`
+}
+
+type followsPage struct {
+	Users      []returnFollow `json:"users"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+}
+
+func (cfg *apiConfig) followUser(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Println("no bearer")
+		w.WriteHeader(401)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+	userID, err := cfg.validateToken(r.Context(), token)
+	if err != nil {
+		log.Printf("jwt error: %v", err)
+		w.WriteHeader(401)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+	id, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		msg := fmt.Sprintf("400 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 400)
+		return
+	}
+	if id == userID {
+		http.Error(w, "400 - you cannot follow yourself", 400)
+		return
+	}
+	followee, err := cfg.db.GetUserByID(r.Context(), id)
+	if err != nil {
+		msg := fmt.Sprintf("404 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 404)
+		return
+	}
+	err = cfg.db.FollowUser(r.Context(), database.FollowUserParams{
+		FollowerID: userID,
+		FolloweeID: followee.ID,
+		CreatedAt:  time.Now(),
+	})
+	if err != nil {
+		http.Error(w, http.StatusText(500), 500)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) unfollowUser(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Println("no bearer")
+		w.WriteHeader(401)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+	userID, err := cfg.validateToken(r.Context(), token)
+	if err != nil {
+		log.Printf("jwt error: %v", err)
+		w.WriteHeader(401)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+	id, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		msg := fmt.Sprintf("400 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 400)
+		return
+	}
+	err = cfg.db.UnfollowUser(r.Context(), database.UnfollowUserParams{
+		FollowerID: userID,
+		FolloweeID: id,
+	})
+	if err != nil {
+		http.Error(w, http.StatusText(500), 500)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) getFollowers(w http.ResponseWriter, r *http.Request) {
+	cfg.listFollows(w, r, func(params database.ListFollowersParams) ([]database.ListFollowersRow, error) {
+		return cfg.db.ListFollowers(r.Context(), params)
+	})
+}
+
+func (cfg *apiConfig) getFollowing(w http.ResponseWriter, r *http.Request) {
+	cfg.listFollows(w, r, func(params database.ListFollowersParams) ([]database.ListFollowersRow, error) {
+		rows, err := cfg.db.ListFollowing(r.Context(), database.ListFollowingParams(params))
+		if err != nil {
+			return nil, err
+		}
+		followers := make([]database.ListFollowersRow, 0, len(rows))
+		for _, row := range rows {
+			followers = append(followers, database.ListFollowersRow(row))
+		}
+		return followers, nil
+	})
+}
+
+// listFollows writes one page of either side of a user's follow graph.
+// Both queries return the same shape, so list only differs in which
+// direction of the follows table it reads.
+func (cfg *apiConfig) listFollows(w http.ResponseWriter, r *http.Request, list func(database.ListFollowersParams) ([]database.ListFollowersRow, error)) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		msg := fmt.Sprintf("400 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 400)
+		return
+	}
+	limit, err := pagination.ParseLimit(r.URL.Query().Get("limit"))
+	if err != nil {
+		msg := fmt.Sprintf("400 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 400)
+		return
+	}
+	params := database.ListFollowersParams{
+		UserID:    userID,
+		PageLimit: limit + 1,
+	}
+	if rawCursor := r.URL.Query().Get("cursor"); rawCursor != "" {
+		cursor, err := pagination.DecodeCursor(rawCursor)
+		if err != nil {
+			msg := fmt.Sprintf("400 - %s", err)
+			log.Printf("%s\n", msg)
+			http.Error(w, msg, 400)
+			return
+		}
+		params.AfterFollowedAt = sql.NullTime{Time: cursor.Time, Valid: true}
+		params.AfterID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
+	}
+	rows, err := list(params)
+	if err != nil {
+		msg := fmt.Sprintf("500 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	page := followsPage{
+		Users: []returnFollow{},
+	}
+	if len(rows) > int(limit) {
+		rows = rows[:limit]
+		last := rows[len(rows)-1]
+		page.NextCursor = pagination.Cursor{Time: last.FollowedAt, ID: last.User.ID}.Encode()
+	}
+	for _, row := range rows {
+		page.Users = append(page.Users, returnFollow{
+			ID:         row.User.ID,
+			Handle:     row.User.Handle.String,
+			FollowedAt: row.FollowedAt,
+		})
+	}
+
+	dat, errMarshal := json.Marshal(page)
+	if errMarshal != nil {
+		msg := fmt.Sprintf("500 - %s", errMarshal)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+	w.Write(dat)
+}
+
+func (cfg *apiConfig) getFeed(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Println("no bearer")
+		w.WriteHeader(401)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+	userID, err := cfg.validateToken(r.Context(), token)
+	if err != nil {
+		log.Printf("jwt error: %v", err)
+		w.WriteHeader(401)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+	limit, err := pagination.ParseLimit(r.URL.Query().Get("limit"))
+	if err != nil {
+		msg := fmt.Sprintf("400 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 400)
+		return
+	}
+	params := database.GetFeedParams{
+		UserID:    userID,
+		PageLimit: limit + 1,
+	}
+	if rawCursor := r.URL.Query().Get("cursor"); rawCursor != "" {
+		cursor, err := pagination.DecodeCursor(rawCursor)
+		if err != nil {
+			msg := fmt.Sprintf("400 - %s", err)
+			log.Printf("%s\n", msg)
+			http.Error(w, msg, 400)
+			return
+		}
+		params.AfterCreatedAt = sql.NullTime{Time: cursor.Time, Valid: true}
+		params.AfterID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
+	}
+	chirps, err := cfg.db.GetFeed(r.Context(), params)
+	if err != nil {
+		msg := fmt.Sprintf("500 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	var page chirpsPage
+	if len(chirps) > int(limit) {
+		chirps = chirps[:limit]
+		last := chirps[len(chirps)-1]
+		page.NextCursor = pagination.Cursor{Time: last.CreatedAt, ID: last.ID}.Encode()
+	}
+	page.Chirps, err = cfg.loadChirpsJSON(r.Context(), uuid.NullUUID{UUID: userID, Valid: true}, chirps)
+	if err != nil {
+		msg := fmt.Sprintf("500 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+
+	dat, errMarshal := json.Marshal(page)
+	if errMarshal != nil {
+		msg := fmt.Sprintf("500 - %s", errMarshal)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+	w.Write(dat)
+}
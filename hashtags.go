@@ -0,0 +1,128 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/uncomfyhalomacro/chirpy/internal/database"
+	"github.com/uncomfyhalomacro/chirpy/internal/entities"
+	"github.com/uncomfyhalomacro/chirpy/internal/pagination"
+	"log"
+	"net/http"
+	"time"
+)
+
+const (
+	defaultTrendingWindow = 24 * time.Hour
+	maxTrendingWindow     = 30 * 24 * time.Hour
+)
+
+func (cfg *apiConfig) getHashtagChirps(w http.ResponseWriter, r *http.Request) {
+	limit, err := pagination.ParseLimit(r.URL.Query().Get("limit"))
+	if err != nil {
+		msg := fmt.Sprintf("400 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 400)
+		return
+	}
+	params := database.ListChirpsByHashtagParams{
+		Tag:       entities.NormalizeHashtag(r.PathValue("tag")),
+		ViewerID:  cfg.viewerID(r),
+		PageLimit: limit + 1,
+	}
+	if rawCursor := r.URL.Query().Get("cursor"); rawCursor != "" {
+		cursor, err := pagination.DecodeCursor(rawCursor)
+		if err != nil {
+			msg := fmt.Sprintf("400 - %s", err)
+			log.Printf("%s\n", msg)
+			http.Error(w, msg, 400)
+			return
+		}
+		params.AfterCreatedAt = sql.NullTime{Time: cursor.Time, Valid: true}
+		params.AfterID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
+	}
+	chirps, err := cfg.db.ListChirpsByHashtag(r.Context(), params)
+	if err != nil {
+		msg := fmt.Sprintf("500 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	var page chirpsPage
+	if len(chirps) > int(limit) {
+		chirps = chirps[:limit]
+		last := chirps[len(chirps)-1]
+		page.NextCursor = pagination.Cursor{Time: last.CreatedAt, ID: last.ID}.Encode()
+	}
+	page.Chirps, err = cfg.loadChirpsJSON(r.Context(), cfg.viewerID(r), chirps)
+	if err != nil {
+		msg := fmt.Sprintf("500 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+
+	dat, errMarshal := json.Marshal(page)
+	if errMarshal != nil {
+		msg := fmt.Sprintf("500 - %s", errMarshal)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+	w.Write(dat)
+}
+
+func (cfg *apiConfig) getTrendingHashtags(w http.ResponseWriter, r *http.Request) {
+	limit, err := pagination.ParseLimit(r.URL.Query().Get("limit"))
+	if err != nil {
+		msg := fmt.Sprintf("400 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 400)
+		return
+	}
+	window := defaultTrendingWindow
+	if value := r.URL.Query().Get("window"); value != "" {
+		window, err = time.ParseDuration(value)
+		if err != nil || window <= 0 || window > maxTrendingWindow {
+			msg := fmt.Sprintf("400 - window should be a duration such as `6h` of at most %s", maxTrendingWindow)
+			log.Printf("%s\n", msg)
+			http.Error(w, msg, 400)
+			return
+		}
+	}
+	rows, err := cfg.db.GetTrendingHashtags(r.Context(), database.GetTrendingHashtagsParams{
+		Since:     time.Now().Add(-window),
+		ViewerID:  cfg.viewerID(r),
+		PageLimit: limit,
+	})
+	if err != nil {
+		msg := fmt.Sprintf("500 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	type returnHashtag struct {
+		Tag  string `json:"tag"`
+		Uses int64  `json:"uses"`
+	}
+	hashtags := []returnHashtag{}
+	for _, row := range rows {
+		hashtags = append(hashtags, returnHashtag{
+			Tag:  row.Tag,
+			Uses: row.Uses,
+		})
+	}
+	dat, errMarshal := json.Marshal(hashtags)
+	if errMarshal != nil {
+		msg := fmt.Sprintf("500 - %s", errMarshal)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+	w.Write(dat)
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/uncomfyhalomacro/chirpy/internal/auth"
+	"github.com/uncomfyhalomacro/chirpy/internal/database"
+	"github.com/uncomfyhalomacro/chirpy/internal/entitlements"
+	"github.com/uncomfyhalomacro/chirpy/internal/importer"
+	"github.com/uncomfyhalomacro/chirpy/internal/validation"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"time"
+)
+
+const (
+	// chirps are saved this many to a transaction, so a failure only
+	// loses one batch of an import
+	importBatchSize = 100
+	maxImportSize   = 64 << 20
+
+	importAccepted = "accepted"
+	importRejected = "rejected"
+)
+
+type importResult struct {
+	Line       int                    `json:"line"`
+	Status     string                 `json:"status"`
+	ID         *uuid.UUID             `json:"id,omitempty"`
+	Error      string                 `json:"error,omitempty"`
+	Violations []validation.Violation `json:"violations,omitempty"`
+}
+
+type importReport struct {
+	Accepted int            `json:"accepted"`
+	Rejected int            `json:"rejected"`
+	Results  []importResult `json:"results"`
+}
+
+func (report *importReport) reject(i int, reason string) {
+	report.Results[i].Status = importRejected
+	report.Results[i].Error = reason
+	report.Rejected++
+}
+
+// importChirps validates records with the same rules as postChirps and
+// saves the valid ones for userID, keeping their original created_at.
+// Records without a date are dated now.
+func (cfg *apiConfig) importChirps(ctx context.Context, userID uuid.UUID, maxLength int, records []importer.Record) importReport {
+	report := importReport{Results: make([]importResult, len(records))}
+	var pending []int
+	bodies := make([]string, len(records))
+	flagged := make([]bool, len(records))
+	now := time.Now()
+	for i, record := range records {
+		report.Results[i].Line = record.Line
+		if record.Err != nil {
+			report.reject(i, record.Err.Error())
+			continue
+		}
+		if record.CreatedAt.After(now) {
+			report.reject(i, "created_at is in the future")
+			continue
+		}
+		cleanedBody, sensitive, violations := cfg.cleanChirpBody(record.Body, maxLength)
+		if len(violations) > 0 {
+			report.reject(i, violations[0].Message)
+			report.Results[i].Violations = violations
+			continue
+		}
+		bodies[i] = cleanedBody
+		flagged[i] = sensitive
+		pending = append(pending, i)
+	}
+
+	for start := 0; start < len(pending); start += importBatchSize {
+		batch := pending[start:min(start+importBatchSize, len(pending))]
+		ids, err := cfg.importBatch(ctx, userID, now, records, bodies, flagged, batch)
+		if err != nil {
+			log.Printf("failed to import a batch of chirps: %v\n", err)
+			for _, i := range batch {
+				report.reject(i, "could not be saved, try again")
+			}
+			continue
+		}
+		for n, i := range batch {
+			report.Results[i].Status = importAccepted
+			report.Results[i].ID = &ids[n]
+			report.Accepted++
+		}
+	}
+	return report
+}
+
+func (cfg *apiConfig) importBatch(ctx context.Context, userID uuid.UUID, now time.Time, records []importer.Record, bodies []string, flagged []bool, batch []int) ([]uuid.UUID, error) {
+	tx, err := cfg.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+	ids := make([]uuid.UUID, 0, len(batch))
+	for _, i := range batch {
+		createdAt := records[i].CreatedAt
+		if createdAt.IsZero() {
+			createdAt = now
+		}
+		chirp, err := qtx.CreateChirp(ctx, database.CreateChirpParams{
+			Body:       bodies[i],
+			CreatedAt:  createdAt,
+			UpdatedAt:  createdAt,
+			UserID:     userID,
+			Visibility: visibilityPublic,
+			Sensitive:  flagged[i],
+		})
+		if err != nil {
+			return nil, err
+		}
+		// @handles in an import belong to another site, so unlike
+		// saveChirpEntities this does not resolve mentions or notify
+		// anyone
+		chirp, err = cfg.saveChirpLinks(ctx, qtx, chirp)
+		if err != nil {
+			return nil, err
+		}
+		if err = saveChirpHashtags(ctx, qtx, chirp); err != nil {
+			return nil, err
+		}
+		ids = append(ids, chirp.ID)
+	}
+	return ids, tx.Commit()
+}
+
+func readImport(rawFormat string, data []byte) ([]importer.Record, error) {
+	format := importer.Detect(data)
+	if rawFormat != "" {
+		var err error
+		if format, err = importer.ParseFormat(rawFormat); err != nil {
+			return nil, err
+		}
+	}
+	return importer.Parse(format, data)
+}
+
+func (cfg *apiConfig) postImport(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Println("no bearer")
+		w.WriteHeader(401)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+	userID, err := cfg.validateToken(r.Context(), token)
+	if err != nil {
+		log.Printf("jwt error: %v", err)
+		w.WriteHeader(401)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+	perks, ok := cfg.callerEntitlements(w, r, userID)
+	if !ok {
+		return
+	}
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxImportSize))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, fmt.Sprintf("413 - imports can be at most %d MiB", maxImportSize>>20), 413)
+			return
+		}
+		msg := fmt.Sprintf("400 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 400)
+		return
+	}
+	records, err := readImport(r.URL.Query().Get("format"), data)
+	if err != nil {
+		msg := fmt.Sprintf("400 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 400)
+		return
+	}
+	// every chirp in the file counts towards the rate limit, so one
+	// import can hold at most a minute's worth
+	count := 0
+	for _, record := range records {
+		if record.Err == nil {
+			count++
+		}
+	}
+	if count > perks.ChirpsPerMinute {
+		http.Error(w, fmt.Sprintf("413 - an import can have at most %d chirps", perks.ChirpsPerMinute), 413)
+		return
+	}
+	if !cfg.limiter.AllowN(userID, perks.ChirpsPerMinute, max(1, count)) {
+		http.Error(w, "429 - you are posting chirps too quickly", 429)
+		return
+	}
+	report := cfg.importChirps(r.Context(), userID, perks.MaxChirpLength, records)
+
+	dat, errMarshal := json.Marshal(report)
+	if errMarshal != nil {
+		msg := fmt.Sprintf("500 - %s", errMarshal)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+	w.Write(dat)
+}
+
+// runImportCommand implements `chirpy import`, which imports a file for a
+// user straight into the database and prints the report as JSON. It
+// returns the exit code.
+func (cfg *apiConfig) runImportCommand(args []string) int {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	email := flags.String("user", "", "email of the user to import the chirps for")
+	format := flags.String("format", "", "jsonl or twitter; guessed from the file when left out")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: chirpy import -user EMAIL [-format jsonl|twitter] FILE\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *email == "" || flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+	ctx := context.Background()
+	user, err := cfg.db.GetUser(ctx, *email)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "no user with email %s: %v\n", *email, err)
+		return 1
+	}
+	data, err := os.ReadFile(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+	records, err := readImport(*format, data)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+	report := cfg.importChirps(ctx, user.ID, entitlements.For(user.IsChirpyRed).MaxChirpLength, records)
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err = encoder.Encode(report); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+	if report.Rejected > 0 {
+		return 1
+	}
+	return 0
+}
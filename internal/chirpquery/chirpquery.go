@@ -0,0 +1,145 @@
+// Package chirpquery parses the query string of the chirp list endpoint
+// into a Query. Every filter is optional; the database applies whichever
+// ones are set.
+package chirpquery
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/uncomfyhalomacro/chirpy/internal/pagination"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Sort string
+
+const (
+	SortCreatedAt Sort = "created_at"
+	SortUpdatedAt Sort = "updated_at"
+	// SortRelevance orders search results by how well they match, best
+	// first. It needs a search query.
+	SortRelevance Sort = "relevance"
+)
+
+type Query struct {
+	// Text is a web search style full-text query.
+	Text      string
+	AuthorIDs []uuid.UUID
+	// Since and Until bound created_at; zero means unbounded. They are in
+	// the local zone, like every timestamp the server stores.
+	Since time.Time
+	Until time.Time
+	// HasMedia keeps only chirps with (true) or without (false)
+	// attachments when set.
+	HasMedia *bool
+	// MinLikes keeps only chirps with at least this many likes.
+	MinLikes   int32
+	Sort       Sort
+	Descending bool
+	Limit      int32
+	Cursor     *pagination.Cursor
+}
+
+// Parse reads a Query from the list endpoint's query string. author_id can
+// be repeated or comma-separated. For compatibility, sort also accepts asc
+// and desc, meaning created_at in that order.
+func Parse(values url.Values) (Query, error) {
+	var q Query
+	var err error
+	if q.Limit, err = pagination.ParseLimit(values.Get("limit")); err != nil {
+		return q, err
+	}
+	q.Text = strings.TrimSpace(values.Get("q"))
+
+	for _, value := range values["author_id"] {
+		for _, raw := range strings.Split(value, ",") {
+			id, err := uuid.Parse(strings.TrimSpace(raw))
+			if err != nil {
+				return q, fmt.Errorf("author_id %q is not a valid ID", raw)
+			}
+			q.AuthorIDs = append(q.AuthorIDs, id)
+		}
+	}
+
+	for name, dst := range map[string]*time.Time{"since": &q.Since, "until": &q.Until} {
+		if value := values.Get(name); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return q, fmt.Errorf("%s should be an RFC 3339 timestamp", name)
+			}
+			*dst = t.In(time.Local)
+		}
+	}
+	if !q.Since.IsZero() && !q.Until.IsZero() && !q.Since.Before(q.Until) {
+		return q, fmt.Errorf("since should be before until")
+	}
+
+	if value := values.Get("has_media"); value != "" {
+		hasMedia, err := strconv.ParseBool(value)
+		if err != nil {
+			return q, fmt.Errorf("has_media should be true or false")
+		}
+		q.HasMedia = &hasMedia
+	}
+	if value := values.Get("min_likes"); value != "" {
+		n, err := strconv.ParseInt(value, 10, 32)
+		if err != nil || n < 0 {
+			return q, fmt.Errorf("min_likes should be a non-negative integer")
+		}
+		q.MinLikes = int32(n)
+	}
+
+	if err = q.parseSort(values.Get("sort"), values.Get("order")); err != nil {
+		return q, err
+	}
+
+	if raw := values.Get("cursor"); raw != "" {
+		cursor, err := pagination.DecodeCursor(raw)
+		if err != nil {
+			return q, err
+		}
+		q.Cursor = &cursor
+	}
+	return q, nil
+}
+
+func (q *Query) parseSort(sort, order string) error {
+	switch order {
+	case "", "asc", "desc":
+	default:
+		return fmt.Errorf("order should be asc or desc")
+	}
+	switch sort {
+	case "":
+		q.Sort = SortCreatedAt
+		if q.Text != "" {
+			q.Sort = SortRelevance
+		}
+	case "asc", "desc":
+		if order != "" && order != sort {
+			return fmt.Errorf("sort=%s contradicts order=%s", sort, order)
+		}
+		q.Sort = SortCreatedAt
+		order = sort
+	case string(SortCreatedAt), string(SortUpdatedAt):
+		q.Sort = Sort(sort)
+	case string(SortRelevance):
+		if q.Text == "" {
+			return fmt.Errorf("sort=relevance needs a search query in q")
+		}
+		q.Sort = SortRelevance
+	default:
+		return fmt.Errorf("sort should be one of created_at, updated_at or relevance")
+	}
+	if q.Sort == SortRelevance {
+		if order == "asc" {
+			return fmt.Errorf("relevance can only be sorted best first")
+		}
+		q.Descending = true
+		return nil
+	}
+	q.Descending = order == "desc"
+	return nil
+}
@@ -0,0 +1,97 @@
+package chirpquery
+
+import (
+	"github.com/google/uuid"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestParseDefaults(t *testing.T) {
+	q, err := Parse(url.Values{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v\n", err)
+	}
+	if q.Sort != SortCreatedAt || q.Descending {
+		t.Errorf("expected created_at ascending, got %s descending=%v\n", q.Sort, q.Descending)
+	}
+	if q.Limit == 0 || q.Cursor != nil || q.HasMedia != nil || len(q.AuthorIDs) != 0 {
+		t.Errorf("unexpected filters in %+v\n", q)
+	}
+}
+
+func TestParseSearchSortsByRelevance(t *testing.T) {
+	q, err := Parse(url.Values{"q": {"gopher"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v\n", err)
+	}
+	if q.Sort != SortRelevance || !q.Descending {
+		t.Errorf("expected relevance descending, got %s descending=%v\n", q.Sort, q.Descending)
+	}
+}
+
+func TestParseLegacySort(t *testing.T) {
+	q, err := Parse(url.Values{"sort": {"desc"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v\n", err)
+	}
+	if q.Sort != SortCreatedAt || !q.Descending {
+		t.Errorf("expected created_at descending, got %s descending=%v\n", q.Sort, q.Descending)
+	}
+}
+
+func TestParseFilters(t *testing.T) {
+	a, b, c := uuid.New(), uuid.New(), uuid.New()
+	q, err := Parse(url.Values{
+		"author_id": {a.String(), b.String() + "," + c.String()},
+		"since":     {"2025-01-01T00:00:00Z"},
+		"until":     {"2025-02-01T00:00:00Z"},
+		"has_media": {"false"},
+		"min_likes": {"3"},
+		"sort":      {"updated_at"},
+		"order":     {"desc"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v\n", err)
+	}
+	if len(q.AuthorIDs) != 3 || q.AuthorIDs[0] != a || q.AuthorIDs[2] != c {
+		t.Errorf("unexpected authors %v\n", q.AuthorIDs)
+	}
+	if q.Since.IsZero() || q.Until.IsZero() {
+		t.Errorf("expected since and until to be set\n")
+	}
+	if q.Since.Location() != time.Local || !q.Since.Equal(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected since in the local zone, got %v\n", q.Since)
+	}
+	if q.HasMedia == nil || *q.HasMedia {
+		t.Errorf("expected has_media=false\n")
+	}
+	if q.MinLikes != 3 {
+		t.Errorf("expected min_likes=3, got %d\n", q.MinLikes)
+	}
+	if q.Sort != SortUpdatedAt || !q.Descending {
+		t.Errorf("expected updated_at descending, got %s descending=%v\n", q.Sort, q.Descending)
+	}
+}
+
+func TestParseRejectsBadInput(t *testing.T) {
+	cases := []url.Values{
+		{"author_id": {"nope"}},
+		{"since": {"yesterday"}},
+		{"since": {"2025-02-01T00:00:00Z"}, "until": {"2025-01-01T00:00:00Z"}},
+		{"has_media": {"maybe"}},
+		{"min_likes": {"-1"}},
+		{"sort": {"likes"}},
+		{"sort": {"relevance"}},
+		{"sort": {"asc"}, "order": {"desc"}},
+		{"q": {"gopher"}, "order": {"asc"}},
+		{"order": {"up"}},
+		{"limit": {"0"}},
+		{"cursor": {"garbage"}},
+	}
+	for _, values := range cases {
+		if _, err := Parse(values); err == nil {
+			t.Errorf("expected an error for %v\n", values)
+		}
+	}
+}
@@ -7,27 +7,57 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const chirpHasReplies = `-- name: ChirpHasReplies :one
+SELECT EXISTS(
+	SELECT 1 FROM chirps
+	WHERE parent_chirp_id=$1::uuid
+)
+`
+
+func (q *Queries) ChirpHasReplies(ctx context.Context, id uuid.UUID) (bool, error) {
+	row := q.db.QueryRowContext(ctx, chirpHasReplies, id)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
 const createChirp = `-- name: CreateChirp :one
-INSERT INTO chirps(body, created_at, updated_at, user_id)
+INSERT INTO chirps(body, created_at, updated_at, user_id, parent_chirp_id, rechirp_of_id, quote_of_id, publish_at, visibility, content_warning, sensitive)
 VALUES (
 	$1,
 	$2,
 	$3,
-	$4
+	$4,
+	$5,
+	$6,
+	$7,
+	$8,
+	$9,
+	$10,
+	$11
 )
-RETURNING id, created_at, updated_at, body, user_id
+RETURNING id, created_at, updated_at, body, user_id, edited_at, parent_chirp_id, is_tombstone, rechirp_of_id, quote_of_id, deleted_at, publish_at, visibility, content_warning, sensitive, publish_failed_at
 `
 
 type CreateChirpParams struct {
-	Body      string
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	UserID    uuid.UUID
+	Body           string
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	UserID         uuid.UUID
+	ParentChirpID  uuid.NullUUID
+	RechirpOfID    uuid.NullUUID
+	QuoteOfID      uuid.NullUUID
+	PublishAt      sql.NullTime
+	Visibility     string
+	ContentWarning sql.NullString
+	Sensitive      bool
 }
 
 func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp, error) {
@@ -36,6 +66,13 @@ func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp
 		arg.CreatedAt,
 		arg.UpdatedAt,
 		arg.UserID,
+		arg.ParentChirpID,
+		arg.RechirpOfID,
+		arg.QuoteOfID,
+		arg.PublishAt,
+		arg.Visibility,
+		arg.ContentWarning,
+		arg.Sensitive,
 	)
 	var i Chirp
 	err := row.Scan(
@@ -44,6 +81,17 @@ func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.EditedAt,
+		&i.ParentChirpID,
+		&i.IsTombstone,
+		&i.RechirpOfID,
+		&i.QuoteOfID,
+		&i.DeletedAt,
+		&i.PublishAt,
+		&i.Visibility,
+		&i.ContentWarning,
+		&i.Sensitive,
+		&i.PublishFailedAt,
 	)
 	return i, err
 }
@@ -58,13 +106,52 @@ func (q *Queries) DeleteChirp(ctx context.Context, id uuid.UUID) error {
 	return err
 }
 
+const deleteRechirp = `-- name: DeleteRechirp :execrows
+DELETE FROM chirps
+WHERE user_id=$1 AND rechirp_of_id=$2::uuid
+AND deleted_at IS NULL
+`
+
+type DeleteRechirpParams struct {
+	UserID  uuid.UUID
+	ChirpID uuid.UUID
+}
+
+func (q *Queries) DeleteRechirp(ctx context.Context, arg DeleteRechirpParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteRechirp, arg.UserID, arg.ChirpID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const disownTombstones = `-- name: DisownTombstones :exec
+UPDATE chirps
+SET user_id=NULL
+WHERE user_id=$1 AND is_tombstone
+`
+
+// Lets the tombstones of a user outlive the account instead of going
+// with it.
+func (q *Queries) DisownTombstones(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, disownTombstones, userID)
+	return err
+}
+
 const getChirp = `-- name: GetChirp :one
-SELECT id, created_at, updated_at, body, user_id FROM chirps
-WHERE id=$1 LIMIT 1
+SELECT id, created_at, updated_at, body, user_id, edited_at, parent_chirp_id, is_tombstone, rechirp_of_id, quote_of_id, deleted_at, publish_at, visibility, content_warning, sensitive, publish_failed_at FROM chirps
+WHERE id=$1 AND NOT is_tombstone AND deleted_at IS NULL AND publish_at IS NULL
+AND chirp_visible_to(chirps.visibility, chirps.user_id, $2::uuid)
+LIMIT 1
 `
 
-func (q *Queries) GetChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, getChirp, id)
+type GetChirpParams struct {
+	ID       uuid.UUID
+	ViewerID uuid.NullUUID
+}
+
+func (q *Queries) GetChirp(ctx context.Context, arg GetChirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, getChirp, arg.ID, arg.ViewerID)
 	var i Chirp
 	err := row.Scan(
 		&i.ID,
@@ -72,17 +159,45 @@ func (q *Queries) GetChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.EditedAt,
+		&i.ParentChirpID,
+		&i.IsTombstone,
+		&i.RechirpOfID,
+		&i.QuoteOfID,
+		&i.DeletedAt,
+		&i.PublishAt,
+		&i.Visibility,
+		&i.ContentWarning,
+		&i.Sensitive,
+		&i.PublishFailedAt,
 	)
 	return i, err
 }
 
-const getChirps = `-- name: GetChirps :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps
-ORDER BY created_at ASC
+const getChirpAncestors = `-- name: GetChirpAncestors :many
+WITH RECURSIVE ancestors(id, parent_chirp_id, depth) AS (
+	SELECT c.id, c.parent_chirp_id, 1 FROM chirps AS c
+	WHERE c.id=(SELECT parent_chirp_id FROM chirps WHERE chirps.id=$1::uuid)
+	UNION ALL
+	SELECT c.id, c.parent_chirp_id, ancestors.depth + 1 FROM chirps AS c
+	JOIN ancestors ON c.id=ancestors.parent_chirp_id
+)
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.edited_at, chirps.parent_chirp_id, chirps.is_tombstone, chirps.rechirp_of_id, chirps.quote_of_id, chirps.deleted_at, chirps.publish_at, chirps.visibility, chirps.content_warning, chirps.sensitive, chirps.publish_failed_at FROM chirps
+JOIN ancestors ON chirps.id=ancestors.id
+WHERE chirps.publish_at IS NULL
+AND chirp_visible_to(chirps.visibility, chirps.user_id, $2::uuid)
+ORDER BY ancestors.depth DESC
 `
 
-func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getChirps)
+type GetChirpAncestorsParams struct {
+	ID       uuid.UUID
+	ViewerID uuid.NullUUID
+}
+
+// Deleted and tombstoned ancestors are kept so the chain has no gaps; the
+// caller shows them as tombstones.
+func (q *Queries) GetChirpAncestors(ctx context.Context, arg GetChirpAncestorsParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpAncestors, arg.ID, arg.ViewerID)
 	if err != nil {
 		return nil, err
 	}
@@ -96,6 +211,17 @@ func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.EditedAt,
+			&i.ParentChirpID,
+			&i.IsTombstone,
+			&i.RechirpOfID,
+			&i.QuoteOfID,
+			&i.DeletedAt,
+			&i.PublishAt,
+			&i.Visibility,
+			&i.ContentWarning,
+			&i.Sensitive,
+			&i.PublishFailedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -110,14 +236,40 @@ func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
 	return items, nil
 }
 
-const getChirpsByUserID = `-- name: GetChirpsByUserID :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps
-WHERE user_id=$1
-ORDER BY created_at ASC
+const getChirpDescendants = `-- name: GetChirpDescendants :many
+WITH RECURSIVE descendants(id, depth) AS (
+	SELECT c.id, 1 FROM chirps AS c
+	WHERE c.parent_chirp_id=ANY($1::uuid[])
+	AND c.publish_at IS NULL
+	UNION ALL
+	SELECT c.id, descendants.depth + 1 FROM chirps AS c
+	JOIN descendants ON c.parent_chirp_id=descendants.id
+	WHERE c.publish_at IS NULL
+	AND descendants.depth < $2::int
+)
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.edited_at, chirps.parent_chirp_id, chirps.is_tombstone, chirps.rechirp_of_id, chirps.quote_of_id, chirps.deleted_at, chirps.publish_at, chirps.visibility, chirps.content_warning, chirps.sensitive, chirps.publish_failed_at FROM chirps
+JOIN descendants ON chirps.id=descendants.id
+WHERE chirp_visible_to(chirps.visibility, chirps.user_id, $3::uuid)
+ORDER BY chirps.created_at ASC, chirps.id ASC
+LIMIT $4
 `
 
-func (q *Queries) GetChirpsByUserID(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getChirpsByUserID, userID)
+type GetChirpDescendantsParams struct {
+	RootIds  []uuid.UUID
+	MaxDepth int32
+	ViewerID uuid.NullUUID
+	MaxRows  int32
+}
+
+// The walk goes through deleted and tombstoned replies for the same
+// reason as ListChirpReplies.
+func (q *Queries) GetChirpDescendants(ctx context.Context, arg GetChirpDescendantsParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpDescendants,
+		pq.Array(arg.RootIds),
+		arg.MaxDepth,
+		arg.ViewerID,
+		arg.MaxRows,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -131,6 +283,17 @@ func (q *Queries) GetChirpsByUserID(ctx context.Context, userID uuid.UUID) ([]Ch
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.EditedAt,
+			&i.ParentChirpID,
+			&i.IsTombstone,
+			&i.RechirpOfID,
+			&i.QuoteOfID,
+			&i.DeletedAt,
+			&i.PublishAt,
+			&i.Visibility,
+			&i.ContentWarning,
+			&i.Sensitive,
+			&i.PublishFailedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -144,3 +307,599 @@ func (q *Queries) GetChirpsByUserID(ctx context.Context, userID uuid.UUID) ([]Ch
 	}
 	return items, nil
 }
+
+const getChirpForUpdate = `-- name: GetChirpForUpdate :one
+SELECT id, created_at, updated_at, body, user_id, edited_at, parent_chirp_id, is_tombstone, rechirp_of_id, quote_of_id, deleted_at, publish_at, visibility, content_warning, sensitive, publish_failed_at FROM chirps
+WHERE id=$1 AND NOT is_tombstone AND deleted_at IS NULL AND publish_at IS NULL LIMIT 1
+FOR UPDATE
+`
+
+func (q *Queries) GetChirpForUpdate(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, getChirpForUpdate, id)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.EditedAt,
+		&i.ParentChirpID,
+		&i.IsTombstone,
+		&i.RechirpOfID,
+		&i.QuoteOfID,
+		&i.DeletedAt,
+		&i.PublishAt,
+		&i.Visibility,
+		&i.ContentWarning,
+		&i.Sensitive,
+		&i.PublishFailedAt,
+	)
+	return i, err
+}
+
+const getChirpsByIDs = `-- name: GetChirpsByIDs :many
+SELECT id, created_at, updated_at, body, user_id, edited_at, parent_chirp_id, is_tombstone, rechirp_of_id, quote_of_id, deleted_at, publish_at, visibility, content_warning, sensitive, publish_failed_at FROM chirps
+WHERE id=ANY($1::uuid[])
+AND deleted_at IS NULL AND publish_at IS NULL
+AND chirp_visible_to(chirps.visibility, chirps.user_id, $2::uuid)
+`
+
+type GetChirpsByIDsParams struct {
+	Ids      []uuid.UUID
+	ViewerID uuid.NullUUID
+}
+
+func (q *Queries) GetChirpsByIDs(ctx context.Context, arg GetChirpsByIDsParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsByIDs, pq.Array(arg.Ids), arg.ViewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.EditedAt,
+			&i.ParentChirpID,
+			&i.IsTombstone,
+			&i.RechirpOfID,
+			&i.QuoteOfID,
+			&i.DeletedAt,
+			&i.PublishAt,
+			&i.Visibility,
+			&i.ContentWarning,
+			&i.Sensitive,
+			&i.PublishFailedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getDeletedChirp = `-- name: GetDeletedChirp :one
+SELECT id, created_at, updated_at, body, user_id, edited_at, parent_chirp_id, is_tombstone, rechirp_of_id, quote_of_id, deleted_at, publish_at, visibility, content_warning, sensitive, publish_failed_at FROM chirps
+WHERE id=$1 AND deleted_at IS NOT NULL LIMIT 1
+`
+
+func (q *Queries) GetDeletedChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, getDeletedChirp, id)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.EditedAt,
+		&i.ParentChirpID,
+		&i.IsTombstone,
+		&i.RechirpOfID,
+		&i.QuoteOfID,
+		&i.DeletedAt,
+		&i.PublishAt,
+		&i.Visibility,
+		&i.ContentWarning,
+		&i.Sensitive,
+		&i.PublishFailedAt,
+	)
+	return i, err
+}
+
+const getRechirpCounts = `-- name: GetRechirpCounts :many
+SELECT rechirp_of_id::uuid AS chirp_id, COUNT(*) AS rechirp_count
+FROM chirps
+WHERE rechirp_of_id=ANY($1::uuid[])
+AND deleted_at IS NULL
+GROUP BY rechirp_of_id
+`
+
+type GetRechirpCountsRow struct {
+	ChirpID      uuid.UUID
+	RechirpCount int64
+}
+
+func (q *Queries) GetRechirpCounts(ctx context.Context, chirpIds []uuid.UUID) ([]GetRechirpCountsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getRechirpCounts, pq.Array(chirpIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetRechirpCountsRow
+	for rows.Next() {
+		var i GetRechirpCountsRow
+		if err := rows.Scan(&i.ChirpID, &i.RechirpCount); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listChirpReplies = `-- name: ListChirpReplies :many
+SELECT id, created_at, updated_at, body, user_id, edited_at, parent_chirp_id, is_tombstone, rechirp_of_id, quote_of_id, deleted_at, publish_at, visibility, content_warning, sensitive, publish_failed_at FROM chirps
+WHERE parent_chirp_id=$1::uuid
+AND publish_at IS NULL
+AND ($2::timestamp IS NULL OR (created_at, id) > ($2, $3::uuid))
+AND chirp_visible_to(chirps.visibility, chirps.user_id, $4::uuid)
+ORDER BY created_at ASC, id ASC
+LIMIT $5
+`
+
+type ListChirpRepliesParams struct {
+	ParentID       uuid.UUID
+	AfterCreatedAt sql.NullTime
+	AfterID        uuid.NullUUID
+	ViewerID       uuid.NullUUID
+	PageLimit      int32
+}
+
+// Like GetChirpAncestors this keeps deleted replies, so their own replies
+// stay reachable.
+func (q *Queries) ListChirpReplies(ctx context.Context, arg ListChirpRepliesParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listChirpReplies,
+		arg.ParentID,
+		arg.AfterCreatedAt,
+		arg.AfterID,
+		arg.ViewerID,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.EditedAt,
+			&i.ParentChirpID,
+			&i.IsTombstone,
+			&i.RechirpOfID,
+			&i.QuoteOfID,
+			&i.DeletedAt,
+			&i.PublishAt,
+			&i.Visibility,
+			&i.ContentWarning,
+			&i.Sensitive,
+			&i.PublishFailedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listChirps = `-- name: ListChirps :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.edited_at, chirps.parent_chirp_id, chirps.is_tombstone, chirps.rechirp_of_id, chirps.quote_of_id, chirps.deleted_at, chirps.publish_at, chirps.visibility, chirps.content_warning, chirps.sensitive, chirps.publish_failed_at, COALESCE(ts_rank(to_tsvector('english', body), websearch_to_tsquery('english', $1::text))::real, 0)::real AS rank
+FROM chirps
+WHERE NOT is_tombstone AND deleted_at IS NULL AND publish_at IS NULL
+AND ($1::text IS NULL OR to_tsvector('english', body) @@ websearch_to_tsquery('english', $1::text))
+AND (COALESCE(cardinality($2::uuid[]), 0)=0 OR user_id=ANY($2::uuid[]))
+AND ($3::timestamp IS NULL OR created_at >= $3)
+AND ($4::timestamp IS NULL OR created_at < $4)
+AND ($5::boolean IS NULL OR EXISTS (
+	SELECT 1 FROM chirp_attachments WHERE chirp_attachments.chirp_id=chirps.id
+)=$5)
+AND ($6::int IS NULL OR (
+	SELECT COUNT(*) FROM chirp_likes WHERE chirp_likes.chirp_id=chirps.id
+) >= $6)
+AND chirp_visible_to(chirps.visibility, chirps.user_id, $7::uuid)
+AND ($8::uuid IS NULL OR CASE
+	WHEN $9::text='rank'
+		THEN (COALESCE(ts_rank(to_tsvector('english', body), websearch_to_tsquery('english', $1::text))::real, 0)::real, created_at, id) < ($10::real, $11::timestamp, $8)
+	WHEN $9='updated_at' AND $12::boolean
+		THEN (updated_at, id) < ($11, $8)
+	WHEN $9='updated_at'
+		THEN (updated_at, id) > ($11, $8)
+	WHEN $12
+		THEN (created_at, id) < ($11, $8)
+	ELSE (created_at, id) > ($11, $8)
+END)
+ORDER BY
+	CASE WHEN $9='rank' THEN COALESCE(ts_rank(to_tsvector('english', body), websearch_to_tsquery('english', $1::text))::real, 0)::real END DESC,
+	CASE WHEN $9 IN ('rank', 'created_at') AND $12 THEN created_at END DESC,
+	CASE WHEN $9='created_at' AND NOT $12 THEN created_at END ASC,
+	CASE WHEN $9='updated_at' AND $12 THEN updated_at END DESC,
+	CASE WHEN $9='updated_at' AND NOT $12 THEN updated_at END ASC,
+	CASE WHEN $12 THEN id END DESC,
+	CASE WHEN NOT $12 THEN id END ASC
+LIMIT $13
+`
+
+type ListChirpsParams struct {
+	Query      sql.NullString
+	AuthorIds  []uuid.UUID
+	Since      sql.NullTime
+	Until      sql.NullTime
+	HasMedia   sql.NullBool
+	MinLikes   sql.NullInt32
+	ViewerID   uuid.NullUUID
+	AfterID    uuid.NullUUID
+	SortKey    string
+	AfterRank  sql.NullFloat64
+	AfterTime  sql.NullTime
+	Descending bool
+	PageLimit  int32
+}
+
+type ListChirpsRow struct {
+	Chirp Chirp
+	Rank  float32
+}
+
+// Every filter is skipped when its argument is NULL, so one query serves
+// plain listing, filtering and search. sort_key is created_at, updated_at
+// or rank; rank is only meaningful together with a query and always sorts
+// descending.
+func (q *Queries) ListChirps(ctx context.Context, arg ListChirpsParams) ([]ListChirpsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listChirps,
+		arg.Query,
+		pq.Array(arg.AuthorIds),
+		arg.Since,
+		arg.Until,
+		arg.HasMedia,
+		arg.MinLikes,
+		arg.ViewerID,
+		arg.AfterID,
+		arg.SortKey,
+		arg.AfterRank,
+		arg.AfterTime,
+		arg.Descending,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListChirpsRow
+	for rows.Next() {
+		var i ListChirpsRow
+		if err := rows.Scan(
+			&i.Chirp.ID,
+			&i.Chirp.CreatedAt,
+			&i.Chirp.UpdatedAt,
+			&i.Chirp.Body,
+			&i.Chirp.UserID,
+			&i.Chirp.EditedAt,
+			&i.Chirp.ParentChirpID,
+			&i.Chirp.IsTombstone,
+			&i.Chirp.RechirpOfID,
+			&i.Chirp.QuoteOfID,
+			&i.Chirp.DeletedAt,
+			&i.Chirp.PublishAt,
+			&i.Chirp.Visibility,
+			&i.Chirp.ContentWarning,
+			&i.Chirp.Sensitive,
+			&i.Chirp.PublishFailedAt,
+			&i.Rank,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPurgeableChirps = `-- name: ListPurgeableChirps :many
+SELECT id, created_at, updated_at, body, user_id, edited_at, parent_chirp_id, is_tombstone, rechirp_of_id, quote_of_id, deleted_at, publish_at, visibility, content_warning, sensitive, publish_failed_at FROM chirps
+WHERE deleted_at < $1
+ORDER BY deleted_at ASC
+LIMIT $2
+`
+
+type ListPurgeableChirpsParams struct {
+	DeletedBefore sql.NullTime
+	PageLimit     int32
+}
+
+func (q *Queries) ListPurgeableChirps(ctx context.Context, arg ListPurgeableChirpsParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listPurgeableChirps, arg.DeletedBefore, arg.PageLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.EditedAt,
+			&i.ParentChirpID,
+			&i.IsTombstone,
+			&i.RechirpOfID,
+			&i.QuoteOfID,
+			&i.DeletedAt,
+			&i.PublishAt,
+			&i.Visibility,
+			&i.ContentWarning,
+			&i.Sensitive,
+			&i.PublishFailedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listRepliedUserChirps = `-- name: ListRepliedUserChirps :many
+SELECT id, created_at, updated_at, body, user_id, edited_at, parent_chirp_id, is_tombstone, rechirp_of_id, quote_of_id, deleted_at, publish_at, visibility, content_warning, sensitive, publish_failed_at FROM chirps
+WHERE user_id=$1 AND NOT is_tombstone
+AND EXISTS (SELECT 1 FROM chirps AS replies WHERE replies.parent_chirp_id=chirps.id)
+`
+
+// The chirps of a user that have replies and are not tombstones yet.
+func (q *Queries) ListRepliedUserChirps(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listRepliedUserChirps, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.EditedAt,
+			&i.ParentChirpID,
+			&i.IsTombstone,
+			&i.RechirpOfID,
+			&i.QuoteOfID,
+			&i.DeletedAt,
+			&i.PublishAt,
+			&i.Visibility,
+			&i.ContentWarning,
+			&i.Sensitive,
+			&i.PublishFailedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const restoreChirp = `-- name: RestoreChirp :one
+UPDATE chirps
+SET deleted_at=NULL
+WHERE id=$1
+RETURNING id, created_at, updated_at, body, user_id, edited_at, parent_chirp_id, is_tombstone, rechirp_of_id, quote_of_id, deleted_at, publish_at, visibility, content_warning, sensitive, publish_failed_at
+`
+
+func (q *Queries) RestoreChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, restoreChirp, id)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.EditedAt,
+		&i.ParentChirpID,
+		&i.IsTombstone,
+		&i.RechirpOfID,
+		&i.QuoteOfID,
+		&i.DeletedAt,
+		&i.PublishAt,
+		&i.Visibility,
+		&i.ContentWarning,
+		&i.Sensitive,
+		&i.PublishFailedAt,
+	)
+	return i, err
+}
+
+const setChirpBody = `-- name: SetChirpBody :one
+UPDATE chirps
+SET body=$2
+WHERE id=$1
+RETURNING id, created_at, updated_at, body, user_id, edited_at, parent_chirp_id, is_tombstone, rechirp_of_id, quote_of_id, deleted_at, publish_at, visibility, content_warning, sensitive, publish_failed_at
+`
+
+type SetChirpBodyParams struct {
+	ID   uuid.UUID
+	Body string
+}
+
+func (q *Queries) SetChirpBody(ctx context.Context, arg SetChirpBodyParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, setChirpBody, arg.ID, arg.Body)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.EditedAt,
+		&i.ParentChirpID,
+		&i.IsTombstone,
+		&i.RechirpOfID,
+		&i.QuoteOfID,
+		&i.DeletedAt,
+		&i.PublishAt,
+		&i.Visibility,
+		&i.ContentWarning,
+		&i.Sensitive,
+		&i.PublishFailedAt,
+	)
+	return i, err
+}
+
+const setChirpSensitive = `-- name: SetChirpSensitive :one
+UPDATE chirps
+SET sensitive=$2
+WHERE id=$1 AND deleted_at IS NULL AND NOT is_tombstone
+RETURNING id, created_at, updated_at, body, user_id, edited_at, parent_chirp_id, is_tombstone, rechirp_of_id, quote_of_id, deleted_at, publish_at, visibility, content_warning, sensitive, publish_failed_at
+`
+
+type SetChirpSensitiveParams struct {
+	ID        uuid.UUID
+	Sensitive bool
+}
+
+func (q *Queries) SetChirpSensitive(ctx context.Context, arg SetChirpSensitiveParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, setChirpSensitive, arg.ID, arg.Sensitive)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.EditedAt,
+		&i.ParentChirpID,
+		&i.IsTombstone,
+		&i.RechirpOfID,
+		&i.QuoteOfID,
+		&i.DeletedAt,
+		&i.PublishAt,
+		&i.Visibility,
+		&i.ContentWarning,
+		&i.Sensitive,
+		&i.PublishFailedAt,
+	)
+	return i, err
+}
+
+const softDeleteChirp = `-- name: SoftDeleteChirp :exec
+UPDATE chirps
+SET deleted_at=$2
+WHERE id=$1
+`
+
+type SoftDeleteChirpParams struct {
+	ID        uuid.UUID
+	DeletedAt sql.NullTime
+}
+
+func (q *Queries) SoftDeleteChirp(ctx context.Context, arg SoftDeleteChirpParams) error {
+	_, err := q.db.ExecContext(ctx, softDeleteChirp, arg.ID, arg.DeletedAt)
+	return err
+}
+
+const tombstoneChirp = `-- name: TombstoneChirp :exec
+UPDATE chirps
+SET body='', is_tombstone=true, deleted_at=NULL, content_warning=NULL, sensitive=false
+WHERE id=$1
+`
+
+func (q *Queries) TombstoneChirp(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, tombstoneChirp, id)
+	return err
+}
+
+const updateChirpBody = `-- name: UpdateChirpBody :one
+UPDATE chirps
+SET body=$2, updated_at=$3, edited_at=$3
+WHERE id=$1
+RETURNING id, created_at, updated_at, body, user_id, edited_at, parent_chirp_id, is_tombstone, rechirp_of_id, quote_of_id, deleted_at, publish_at, visibility, content_warning, sensitive, publish_failed_at
+`
+
+type UpdateChirpBodyParams struct {
+	ID        uuid.UUID
+	Body      string
+	UpdatedAt time.Time
+}
+
+func (q *Queries) UpdateChirpBody(ctx context.Context, arg UpdateChirpBodyParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, updateChirpBody, arg.ID, arg.Body, arg.UpdatedAt)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.EditedAt,
+		&i.ParentChirpID,
+		&i.IsTombstone,
+		&i.RechirpOfID,
+		&i.QuoteOfID,
+		&i.DeletedAt,
+		&i.PublishAt,
+		&i.Visibility,
+		&i.ContentWarning,
+		&i.Sensitive,
+		&i.PublishFailedAt,
+	)
+	return i, err
+}
@@ -11,7 +11,69 @@ import (
 	"github.com/google/uuid"
 )
 
+type Bookmark struct {
+	UserID    uuid.UUID
+	ChirpID   uuid.UUID
+	CreatedAt time.Time
+}
+
 type Chirp struct {
+	ID              uuid.UUID
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	Body            string
+	UserID          uuid.NullUUID
+	EditedAt        sql.NullTime
+	ParentChirpID   uuid.NullUUID
+	IsTombstone     bool
+	RechirpOfID     uuid.NullUUID
+	QuoteOfID       uuid.NullUUID
+	DeletedAt       sql.NullTime
+	PublishAt       sql.NullTime
+	Visibility      string
+	ContentWarning  sql.NullString
+	Sensitive       bool
+	PublishFailedAt sql.NullTime
+}
+
+type ChirpAttachment struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	ChirpID     uuid.UUID
+	Position    int32
+	ContentType string
+	Width       int32
+	Height      int32
+}
+
+type ChirpHashtag struct {
+	ChirpID   uuid.UUID
+	HashtagID uuid.UUID
+	CreatedAt time.Time
+}
+
+type ChirpLike struct {
+	UserID    uuid.UUID
+	ChirpID   uuid.UUID
+	CreatedAt time.Time
+}
+
+type ChirpMention struct {
+	ChirpID     uuid.UUID
+	UserID      uuid.UUID
+	StartOffset int32
+	EndOffset   int32
+}
+
+type ChirpRevision struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	ReplacedAt time.Time
+	Body       string
+	ChirpID    uuid.UUID
+}
+
+type Draft struct {
 	ID        uuid.UUID
 	CreatedAt time.Time
 	UpdatedAt time.Time
@@ -19,6 +81,86 @@ type Chirp struct {
 	UserID    uuid.UUID
 }
 
+type ExportJob struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	UserID     uuid.UUID
+	Status     string
+	StartedAt  sql.NullTime
+	FinishedAt sql.NullTime
+	ExpiresAt  sql.NullTime
+}
+
+type Follow struct {
+	FollowerID uuid.UUID
+	FolloweeID uuid.UUID
+	CreatedAt  time.Time
+}
+
+type Hashtag struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	Tag       string
+}
+
+type Link struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	Code      string
+	Url       string
+	ChirpID   uuid.UUID
+}
+
+type LinkClick struct {
+	LinkID   uuid.UUID
+	Bucket   time.Time
+	Referrer string
+	Clicks   int64
+}
+
+type Notification struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	ReadAt    sql.NullTime
+	Kind      string
+	UserID    uuid.UUID
+	ActorID   uuid.UUID
+	ChirpID   uuid.UUID
+}
+
+type Poll struct {
+	ChirpID  uuid.UUID
+	ClosesAt time.Time
+}
+
+type PollOption struct {
+	ID       uuid.UUID
+	ChirpID  uuid.UUID
+	Position int32
+	Label    string
+}
+
+type PollVote struct {
+	ChirpID   uuid.UUID
+	UserID    uuid.UUID
+	OptionID  uuid.UUID
+	CreatedAt time.Time
+}
+
+type ProfanityList struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	Name      string
+	Locale    string
+	Strategy  string
+}
+
+type ProfanityTerm struct {
+	ListID    uuid.UUID
+	Term      string
+	CreatedAt time.Time
+}
+
 type RefreshToken struct {
 	Token     string
 	CreatedAt time.Time
@@ -29,10 +171,14 @@ type RefreshToken struct {
 }
 
 type User struct {
-	ID             uuid.UUID
-	CreatedAt      time.Time
-	UpdatedAt      time.Time
-	Email          string
-	HashedPassword string
-	IsChirpyRed    bool
+	ID              uuid.UUID
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	Email           string
+	HashedPassword  string
+	IsChirpyRed     bool
+	Handle          sql.NullString
+	DeleteAfter     sql.NullTime
+	IsModerator     bool
+	ExpandSensitive bool
 }
@@ -81,7 +81,7 @@ func (q *Queries) GetExpiry(ctx context.Context, arg GetExpiryParams) (GetExpiry
 }
 
 const getUserFromRefreshToken = `-- name: GetUserFromRefreshToken :one
-SELECT users.id, users.created_at, users.updated_at, users.email, users.hashed_password, users.is_chirpy_red FROM users
+SELECT users.id, users.created_at, users.updated_at, users.email, users.hashed_password, users.is_chirpy_red, users.handle, users.delete_after, users.is_moderator, users.expand_sensitive FROM users
 WHERE id=(
 	SELECT refresh_tokens.user_id FROM refresh_tokens
 	WHERE token=$1 LIMIT 1
@@ -98,10 +98,55 @@ func (q *Queries) GetUserFromRefreshToken(ctx context.Context, token string) (Us
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.Handle,
+		&i.DeleteAfter,
+		&i.IsModerator,
+		&i.ExpandSensitive,
 	)
 	return i, err
 }
 
+const listActiveSessions = `-- name: ListActiveSessions :many
+SELECT token, created_at, updated_at, expires_at, revoked_at, user_id FROM refresh_tokens
+WHERE user_id=$1 AND revoked_at IS NULL AND expires_at > $2
+ORDER BY created_at DESC
+`
+
+type ListActiveSessionsParams struct {
+	UserID    uuid.UUID
+	ExpiresAt time.Time
+}
+
+func (q *Queries) ListActiveSessions(ctx context.Context, arg ListActiveSessionsParams) ([]RefreshToken, error) {
+	rows, err := q.db.QueryContext(ctx, listActiveSessions, arg.UserID, arg.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []RefreshToken
+	for rows.Next() {
+		var i RefreshToken
+		if err := rows.Scan(
+			&i.Token,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ExpiresAt,
+			&i.RevokedAt,
+			&i.UserID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const revokeToken = `-- name: RevokeToken :exec
 UPDATE refresh_tokens
 SET revoked_at=$2, updated_at=$2
@@ -117,3 +162,19 @@ func (q *Queries) RevokeToken(ctx context.Context, arg RevokeTokenParams) error
 	_, err := q.db.ExecContext(ctx, revokeToken, arg.Token, arg.RevokedAt)
 	return err
 }
+
+const revokeUserTokens = `-- name: RevokeUserTokens :exec
+UPDATE refresh_tokens
+SET revoked_at=$2, updated_at=$2
+WHERE user_id=$1 AND revoked_at IS NULL
+`
+
+type RevokeUserTokensParams struct {
+	UserID    uuid.UUID
+	RevokedAt sql.NullTime
+}
+
+func (q *Queries) RevokeUserTokens(ctx context.Context, arg RevokeUserTokensParams) error {
+	_, err := q.db.ExecContext(ctx, revokeUserTokens, arg.UserID, arg.RevokedAt)
+	return err
+}
@@ -7,20 +7,54 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const cancelUserDeletion = `-- name: CancelUserDeletion :one
+UPDATE users
+SET delete_after=NULL
+WHERE id=$1 AND delete_after > $2
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, handle, delete_after, is_moderator, expand_sensitive
+`
+
+type CancelUserDeletionParams struct {
+	ID  uuid.UUID
+	Now sql.NullTime
+}
+
+// Only accounts still inside their grace period can be restored.
+func (q *Queries) CancelUserDeletion(ctx context.Context, arg CancelUserDeletionParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, cancelUserDeletion, arg.ID, arg.Now)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.Handle,
+		&i.DeleteAfter,
+		&i.IsModerator,
+		&i.ExpandSensitive,
+	)
+	return i, err
+}
+
 const createUser = `-- name: CreateUser :one
-INSERT INTO users(created_at, updated_at, email, hashed_password)
+INSERT INTO users(created_at, updated_at, email, hashed_password, handle)
 VALUES (
 	$1,
 	$2,
 	$3,
-	$4
+	$4,
+	$5
 )
-RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, handle, delete_after, is_moderator, expand_sensitive
 `
 
 type CreateUserParams struct {
@@ -28,6 +62,7 @@ type CreateUserParams struct {
 	UpdatedAt      time.Time
 	Email          string
 	HashedPassword string
+	Handle         sql.NullString
 }
 
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
@@ -36,6 +71,7 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		arg.UpdatedAt,
 		arg.Email,
 		arg.HashedPassword,
+		arg.Handle,
 	)
 	var i User
 	err := row.Scan(
@@ -45,12 +81,26 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.Handle,
+		&i.DeleteAfter,
+		&i.IsModerator,
+		&i.ExpandSensitive,
 	)
 	return i, err
 }
 
+const deleteUser = `-- name: DeleteUser :exec
+DELETE FROM users
+WHERE id=$1
+`
+
+func (q *Queries) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteUser, id)
+	return err
+}
+
 const getUser = `-- name: GetUser :one
-SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red FROM users
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, handle, delete_after, is_moderator, expand_sensitive FROM users
 WHERE email=$1 LIMIT 1
 `
 
@@ -64,10 +114,122 @@ func (q *Queries) GetUser(ctx context.Context, email string) (User, error) {
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.Handle,
+		&i.DeleteAfter,
+		&i.IsModerator,
+		&i.ExpandSensitive,
 	)
 	return i, err
 }
 
+const getUserByID = `-- name: GetUserByID :one
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, handle, delete_after, is_moderator, expand_sensitive FROM users
+WHERE id=$1 LIMIT 1
+`
+
+func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByID, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.Handle,
+		&i.DeleteAfter,
+		&i.IsModerator,
+		&i.ExpandSensitive,
+	)
+	return i, err
+}
+
+const getUsersByHandles = `-- name: GetUsersByHandles :many
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, handle, delete_after, is_moderator, expand_sensitive FROM users
+WHERE handle=ANY($1::text[])
+`
+
+func (q *Queries) GetUsersByHandles(ctx context.Context, handles []string) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, getUsersByHandles, pq.Array(handles))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []User
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Email,
+			&i.HashedPassword,
+			&i.IsChirpyRed,
+			&i.Handle,
+			&i.DeleteAfter,
+			&i.IsModerator,
+			&i.ExpandSensitive,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listDueUserDeletions = `-- name: ListDueUserDeletions :many
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, handle, delete_after, is_moderator, expand_sensitive FROM users
+WHERE delete_after <= $1
+ORDER BY delete_after
+LIMIT $2
+`
+
+type ListDueUserDeletionsParams struct {
+	DueBy     sql.NullTime
+	PageLimit int32
+}
+
+func (q *Queries) ListDueUserDeletions(ctx context.Context, arg ListDueUserDeletionsParams) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, listDueUserDeletions, arg.DueBy, arg.PageLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []User
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Email,
+			&i.HashedPassword,
+			&i.IsChirpyRed,
+			&i.Handle,
+			&i.DeleteAfter,
+			&i.IsModerator,
+			&i.ExpandSensitive,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const resetUsers = `-- name: ResetUsers :exec
 DELETE FROM users
 `
@@ -77,11 +239,71 @@ func (q *Queries) ResetUsers(ctx context.Context) error {
 	return err
 }
 
+const scheduleUserDeletion = `-- name: ScheduleUserDeletion :one
+UPDATE users
+SET delete_after=$2
+WHERE id=$1 AND delete_after IS NULL
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, handle, delete_after, is_moderator, expand_sensitive
+`
+
+type ScheduleUserDeletionParams struct {
+	ID          uuid.UUID
+	DeleteAfter sql.NullTime
+}
+
+func (q *Queries) ScheduleUserDeletion(ctx context.Context, arg ScheduleUserDeletionParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, scheduleUserDeletion, arg.ID, arg.DeleteAfter)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.Handle,
+		&i.DeleteAfter,
+		&i.IsModerator,
+		&i.ExpandSensitive,
+	)
+	return i, err
+}
+
+const setExpandSensitive = `-- name: SetExpandSensitive :one
+UPDATE users
+SET expand_sensitive=$2
+WHERE id=$1
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, handle, delete_after, is_moderator, expand_sensitive
+`
+
+type SetExpandSensitiveParams struct {
+	ID              uuid.UUID
+	ExpandSensitive bool
+}
+
+func (q *Queries) SetExpandSensitive(ctx context.Context, arg SetExpandSensitiveParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, setExpandSensitive, arg.ID, arg.ExpandSensitive)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.Handle,
+		&i.DeleteAfter,
+		&i.IsModerator,
+		&i.ExpandSensitive,
+	)
+	return i, err
+}
+
 const updateUserDetails = `-- name: UpdateUserDetails :one
 UPDATE users
 SET email=$1, hashed_password=$2
 WHERE id=$3
-RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, handle, delete_after, is_moderator, expand_sensitive
 `
 
 type UpdateUserDetailsParams struct {
@@ -100,6 +322,40 @@ func (q *Queries) UpdateUserDetails(ctx context.Context, arg UpdateUserDetailsPa
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.Handle,
+		&i.DeleteAfter,
+		&i.IsModerator,
+		&i.ExpandSensitive,
+	)
+	return i, err
+}
+
+const updateUserHandle = `-- name: UpdateUserHandle :one
+UPDATE users
+SET handle=$2
+WHERE id=$1
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, handle, delete_after, is_moderator, expand_sensitive
+`
+
+type UpdateUserHandleParams struct {
+	ID     uuid.UUID
+	Handle sql.NullString
+}
+
+func (q *Queries) UpdateUserHandle(ctx context.Context, arg UpdateUserHandleParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserHandle, arg.ID, arg.Handle)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.Handle,
+		&i.DeleteAfter,
+		&i.IsModerator,
+		&i.ExpandSensitive,
 	)
 	return i, err
 }
@@ -108,7 +364,7 @@ const upgradeUserToChirpyRed = `-- name: UpgradeUserToChirpyRed :one
 UPDATE users
 SET is_chirpy_red=true
 WHERE id=$1
-RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, handle, delete_after, is_moderator, expand_sensitive
 `
 
 func (q *Queries) UpgradeUserToChirpyRed(ctx context.Context, id uuid.UUID) (User, error) {
@@ -121,6 +377,10 @@ func (q *Queries) UpgradeUserToChirpyRed(ctx context.Context, id uuid.UUID) (Use
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.Handle,
+		&i.DeleteAfter,
+		&i.IsModerator,
+		&i.ExpandSensitive,
 	)
 	return i, err
 }
@@ -0,0 +1,72 @@
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// Queries that read chirps without chirp_visible_to, because they only
+// run for the chirp's author after an ownership check or for the server's
+// own background work. Anything else that reads chirps has to go through
+// the visibility check.
+var visibilityExempt = map[string]string{
+	"ListUserAttachments":   "account deletion",
+	"GetChirpForUpdate":     "author only, checked in Go",
+	"DeleteChirp":           "purger",
+	"DeleteRechirp":         "author only",
+	"GetDeletedChirp":       "author only, checked in Go",
+	"ListPurgeableChirps":   "purger",
+	"GetRechirpCounts":      "counts only, for chirps already loaded",
+	"ListRepliedUserChirps": "account deletion",
+	"ChirpHasReplies":       "purger",
+	"ListChirpsForExport":   "author only",
+	"ListScheduledChirps":   "author only",
+	"GetScheduledChirp":     "author only, checked in Go",
+	"CancelScheduledChirp":  "author only",
+	"ListDueChirps":         "scheduler",
+	"LockDueChirp":          "scheduler",
+}
+
+var (
+	queryNameRe  = regexp.MustCompile(`(?m)^-- name: (\w+) :\w+\n`)
+	readsChirpRe = regexp.MustCompile(`\b(FROM|JOIN)\s+chirps\b`)
+)
+
+func TestQueriesCheckVisibility(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("..", "..", "sql", "queries", "*.sql"))
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("no queries found: %v\n", err)
+	}
+	seen := map[string]bool{}
+	for _, path := range paths {
+		dat, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("%v\n", err)
+		}
+		text := string(dat)
+		matches := queryNameRe.FindAllStringSubmatchIndex(text, -1)
+		for i, match := range matches {
+			name := text[match[2]:match[3]]
+			seen[name] = true
+			end := len(text)
+			if i+1 < len(matches) {
+				end = matches[i+1][0]
+			}
+			body := text[match[1]:end]
+			if !readsChirpRe.MatchString(body) || strings.Contains(body, "chirp_visible_to(") {
+				continue
+			}
+			if _, ok := visibilityExempt[name]; !ok {
+				t.Errorf("%s in %s reads chirps without chirp_visible_to\n", name, filepath.Base(path))
+			}
+		}
+	}
+	for name := range visibilityExempt {
+		if !seen[name] {
+			t.Errorf("%s is exempt but no longer exists\n", name)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package entities
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxHashtagLength is the longest hashtag, in characters, that is still
+// treated as a tag.
+const MaxHashtagLength = 100
+
+func isTagRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r)
+}
+
+// NormalizeHashtag turns user input such as "#Go" into the form hashtags
+// are stored in.
+func NormalizeHashtag(tag string) string {
+	return strings.ToLower(strings.TrimPrefix(tag, "#"))
+}
+
+// Hashtags returns the distinct hashtags in body, normalized and in the
+// order they first appear. A hashtag is a '#' that does not follow a word
+// character, followed by word characters of which at least one is a letter.
+func Hashtags(body string) []string {
+	var tags []string
+	seen := map[string]bool{}
+	prev := ' '
+	for i := 0; i < len(body); {
+		r, size := utf8.DecodeRuneInString(body[i:])
+		if r != '#' || isTagRune(prev) {
+			prev = r
+			i += size
+			continue
+		}
+		start := i + size
+		end := start
+		hasLetter := false
+		for end < len(body) {
+			r, size := utf8.DecodeRuneInString(body[end:])
+			if !isTagRune(r) {
+				break
+			}
+			hasLetter = hasLetter || unicode.IsLetter(r)
+			end += size
+		}
+		tag := NormalizeHashtag(body[start:end])
+		if hasLetter && utf8.RuneCountInString(tag) <= MaxHashtagLength && !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+		prev = r
+		if end > start {
+			prev, _ = utf8.DecodeLastRuneInString(body[start:end])
+		}
+		i = end
+	}
+	return tags
+}
@@ -0,0 +1,51 @@
+package entities
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHashtags(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected []string
+	}{
+		{
+			input:    "no tags here",
+			expected: nil,
+		},
+		{
+			input:    "#golang is fun",
+			expected: []string{"golang"},
+		},
+		{
+			input:    "Learning #Go and #SQL, then more #go!",
+			expected: []string{"go", "sql"},
+		},
+		{
+			input:    "issue#42 and #42 are not tags but #v2 is",
+			expected: []string{"v2"},
+		},
+		{
+			input:    "##double #snake_case #café",
+			expected: []string{"double", "snake_case", "café"},
+		},
+		{
+			input:    "trailing # and #",
+			expected: nil,
+		},
+	}
+
+	for _, testCase := range testCases {
+		tags := Hashtags(testCase.input)
+		if !reflect.DeepEqual(tags, testCase.expected) {
+			t.Errorf("hashtags do not match for `%s`: %v vs %v\n", testCase.input, tags, testCase.expected)
+		}
+	}
+}
+
+func TestNormalizeHashtag(t *testing.T) {
+	if tag := NormalizeHashtag("#GoLang"); tag != "golang" {
+		t.Errorf("unexpected normalized tag: %s\n", tag)
+	}
+}
@@ -0,0 +1,41 @@
+package entities
+
+import (
+	"regexp"
+	"strings"
+)
+
+var linkRe = regexp.MustCompile(`(?i)\bhttps?://[^\s<>"]+`)
+
+// Link is a URL found in a chirp body. Start and End are byte offsets
+// into the body, End exclusive.
+type Link struct {
+	URL   string
+	Start int
+	End   int
+}
+
+// Links returns the http and https URLs in body in the order they appear.
+// Punctuation that usually ends the surrounding sentence rather than the
+// URL is left out, as is a closing parenthesis without a matching opening
+// one in the URL.
+func Links(body string) []Link {
+	var links []Link
+	for _, loc := range linkRe.FindAllStringIndex(body, -1) {
+		url := body[loc[0]:loc[1]]
+		for len(url) > 0 {
+			last := url[len(url)-1]
+			if strings.IndexByte(".,;:!?'", last) >= 0 ||
+				(last == ')' && strings.Count(url, ")") > strings.Count(url, "(")) {
+				url = url[:len(url)-1]
+				continue
+			}
+			break
+		}
+		if strings.HasSuffix(url, "://") {
+			continue
+		}
+		links = append(links, Link{URL: url, Start: loc[0], End: loc[0] + len(url)})
+	}
+	return links
+}
@@ -0,0 +1,33 @@
+package entities
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLinks(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected []string
+	}{
+		{input: "no links here", expected: nil},
+		{input: "see https://example.com/a?b=c#d", expected: []string{"https://example.com/a?b=c#d"}},
+		{input: "HTTP://EXAMPLE.COM and http://x.y/z.", expected: []string{"HTTP://EXAMPLE.COM", "http://x.y/z"}},
+		{input: "(see https://en.wikipedia.org/wiki/Go_(programming_language))", expected: []string{"https://en.wikipedia.org/wiki/Go_(programming_language)"}},
+		{input: "(https://example.com)", expected: []string{"https://example.com"}},
+		{input: "ftp://example.com and https:// alone", expected: nil},
+		{input: "nohttps://example.com", expected: nil},
+	}
+	for _, testCase := range testCases {
+		var got []string
+		for _, link := range Links(testCase.input) {
+			if testCase.input[link.Start:link.End] != link.URL {
+				t.Errorf("offsets of `%s` in `%s` do not match\n", link.URL, testCase.input)
+			}
+			got = append(got, link.URL)
+		}
+		if !reflect.DeepEqual(got, testCase.expected) {
+			t.Errorf("links in `%s` do not match: %v vs %v\n", testCase.input, got, testCase.expected)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package entities
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	MinHandleLength = 3
+	MaxHandleLength = 30
+)
+
+// Mention is an @handle found in a chirp body. Start and End are offsets in
+// Unicode code points, End being exclusive, and cover the leading '@'.
+type Mention struct {
+	Handle string
+	Start  int
+	End    int
+}
+
+func isHandleByte(b byte) bool {
+	return b == '_' || ('a' <= b && b <= 'z') || ('A' <= b && b <= 'Z') || ('0' <= b && b <= '9')
+}
+
+// NormalizeHandle turns user input such as "@Bob" into the form handles are
+// stored in.
+func NormalizeHandle(handle string) string {
+	return strings.ToLower(strings.TrimPrefix(handle, "@"))
+}
+
+// ValidHandle reports whether a normalized handle can be registered.
+func ValidHandle(handle string) bool {
+	if len(handle) < MinHandleLength || len(handle) > MaxHandleLength {
+		return false
+	}
+	for i := 0; i < len(handle); i++ {
+		if !isHandleByte(handle[i]) || ('A' <= handle[i] && handle[i] <= 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+// Mentions returns every @handle in body in the order they appear. An '@'
+// directly after a word character, as in an email address, does not start
+// a mention.
+func Mentions(body string) []Mention {
+	var mentions []Mention
+	runeOffset := 0
+	prevIsWord := false
+	for i := 0; i < len(body); {
+		r, size := utf8.DecodeRuneInString(body[i:])
+		if r != '@' || prevIsWord {
+			prevIsWord = r < utf8.RuneSelf && isHandleByte(byte(r))
+			runeOffset++
+			i += size
+			continue
+		}
+		end := i + 1
+		for end < len(body) && isHandleByte(body[end]) {
+			end++
+		}
+		handle := NormalizeHandle(body[i:end])
+		if ValidHandle(handle) {
+			mentions = append(mentions, Mention{
+				Handle: handle,
+				Start:  runeOffset,
+				End:    runeOffset + end - i,
+			})
+		}
+		// handles are ASCII, so bytes and code points line up here
+		runeOffset += end - i
+		prevIsWord = end > i+1
+		i = end
+	}
+	return mentions
+}
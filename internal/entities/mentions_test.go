@@ -0,0 +1,60 @@
+package entities
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMentions(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected []Mention
+	}{
+		{
+			input:    "no mentions here",
+			expected: nil,
+		},
+		{
+			input:    "hey @Bob_1 and @alice!",
+			expected: []Mention{{Handle: "bob_1", Start: 4, End: 10}, {Handle: "alice", Start: 15, End: 21}},
+		},
+		{
+			input:    "mail me at bob@example.com",
+			expected: nil,
+		},
+		{
+			input:    "héllo @bob",
+			expected: []Mention{{Handle: "bob", Start: 6, End: 10}},
+		},
+		{
+			input:    "@ab is too short, @@carol is fine",
+			expected: []Mention{{Handle: "carol", Start: 19, End: 25}},
+		},
+	}
+
+	for _, testCase := range testCases {
+		mentions := Mentions(testCase.input)
+		if !reflect.DeepEqual(mentions, testCase.expected) {
+			t.Errorf("mentions do not match for `%s`: %v vs %v\n", testCase.input, mentions, testCase.expected)
+		}
+	}
+}
+
+func TestValidHandle(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected bool
+	}{
+		{input: "bob", expected: true},
+		{input: "snake_case_99", expected: true},
+		{input: "ab", expected: false},
+		{input: "Bob", expected: false},
+		{input: "bob-smith", expected: false},
+		{input: "abcdefghijklmnopqrstuvwxyz01234", expected: false},
+	}
+	for _, testCase := range testCases {
+		if valid := ValidHandle(testCase.input); valid != testCase.expected {
+			t.Errorf("unexpected result for handle `%s`: %v\n", testCase.input, valid)
+		}
+	}
+}
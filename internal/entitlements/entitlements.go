@@ -0,0 +1,39 @@
+package entitlements
+
+// Entitlements are what a user's plan lets them do. Handlers ask for the
+// caller's Entitlements and check the field they care about, so a new perk
+// is a new field here rather than another plan check in every handler.
+type Entitlements struct {
+	// MaxChirpLength is the longest chirp body, in characters.
+	MaxChirpLength int
+	// CanEditChirps allows editing chirps after they are posted.
+	CanEditChirps bool
+	// MaxAttachments is how many images a chirp may carry.
+	MaxAttachments int
+	// ChirpsPerMinute is the sustained rate at which chirps can be
+	// posted. Up to this many can also be posted in a single burst.
+	ChirpsPerMinute int
+}
+
+var (
+	Free = Entitlements{
+		MaxChirpLength:  140,
+		CanEditChirps:   false,
+		MaxAttachments:  4,
+		ChirpsPerMinute: 10,
+	}
+	ChirpyRed = Entitlements{
+		MaxChirpLength:  500,
+		CanEditChirps:   true,
+		MaxAttachments:  8,
+		ChirpsPerMinute: 60,
+	}
+)
+
+// For returns the entitlements of a user.
+func For(isChirpyRed bool) Entitlements {
+	if isChirpyRed {
+		return ChirpyRed
+	}
+	return Free
+}
@@ -0,0 +1,27 @@
+package entitlements
+
+import "testing"
+
+func TestFor(t *testing.T) {
+	if For(false) != Free {
+		t.Errorf("expected free entitlements for a regular user\n")
+	}
+	if For(true) != ChirpyRed {
+		t.Errorf("expected Chirpy Red entitlements for a red user\n")
+	}
+}
+
+func TestChirpyRedIsNeverWorse(t *testing.T) {
+	if ChirpyRed.MaxChirpLength < Free.MaxChirpLength {
+		t.Errorf("red chirp length %d is below free %d\n", ChirpyRed.MaxChirpLength, Free.MaxChirpLength)
+	}
+	if Free.CanEditChirps && !ChirpyRed.CanEditChirps {
+		t.Errorf("free users can edit but red users cannot\n")
+	}
+	if ChirpyRed.MaxAttachments < Free.MaxAttachments {
+		t.Errorf("red attachments %d are below free %d\n", ChirpyRed.MaxAttachments, Free.MaxAttachments)
+	}
+	if ChirpyRed.ChirpsPerMinute < Free.ChirpsPerMinute {
+		t.Errorf("red rate %d is below free %d\n", ChirpyRed.ChirpsPerMinute, Free.ChirpsPerMinute)
+	}
+}
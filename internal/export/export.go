@@ -0,0 +1,132 @@
+// Package export writes a user's personal data archive: a zip with one JSON
+// file per kind of data and an index.html to read it all in a browser.
+package export
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"github.com/google/uuid"
+	"html/template"
+	"io"
+	"time"
+)
+
+type Profile struct {
+	ID        uuid.UUID `json:"id"`
+	Email     string    `json:"email"`
+	Handle    string    `json:"handle,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type Chirp struct {
+	ID         uuid.UUID `json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	Body       string    `json:"body"`
+	Visibility string    `json:"visibility"`
+	// ContentWarning is empty for chirps without one.
+	ContentWarning string     `json:"content_warning,omitempty"`
+	Sensitive      bool       `json:"sensitive"`
+	ReplyTo        *uuid.UUID `json:"reply_to,omitempty"`
+	RechirpOf      *uuid.UUID `json:"rechirp_of,omitempty"`
+	QuoteOf        *uuid.UUID `json:"quote_of,omitempty"`
+	EditedAt       *time.Time `json:"edited_at,omitempty"`
+	PublishAt      *time.Time `json:"publish_at,omitempty"`
+	DeletedAt      *time.Time `json:"deleted_at,omitempty"`
+}
+
+// Session is a signed-in device, i.e. a refresh token that is neither
+// revoked nor expired. The token itself is left out of the archive.
+type Session struct {
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type ChirpyRed struct {
+	Active          bool `json:"active"`
+	MaxChirpLength  int  `json:"max_chirp_length"`
+	CanEditChirps   bool `json:"can_edit_chirps"`
+	MaxAttachments  int  `json:"max_attachments"`
+	ChirpsPerMinute int  `json:"chirps_per_minute"`
+}
+
+type Archive struct {
+	GeneratedAt time.Time
+	Profile     Profile
+	ChirpyRed   ChirpyRed
+	Chirps      []Chirp
+	Sessions    []Session
+}
+
+var index = template.Must(template.New("index.html").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Chirpy data export for {{.Profile.Email}}</title>
+</head>
+<body>
+<h1>Chirpy data export</h1>
+<p>Generated {{.GeneratedAt.Format "2006-01-02 15:04:05 MST"}}. The same data is in the JSON files next to this page.</p>
+<h2>Profile</h2>
+<dl>
+<dt>ID</dt><dd>{{.Profile.ID}}</dd>
+<dt>Email</dt><dd>{{.Profile.Email}}</dd>
+{{if .Profile.Handle}}<dt>Handle</dt><dd>@{{.Profile.Handle}}</dd>{{end}}
+<dt>Joined</dt><dd>{{.Profile.CreatedAt.Format "2006-01-02"}}</dd>
+<dt>Chirpy Red</dt><dd>{{if .ChirpyRed.Active}}yes{{else}}no{{end}}</dd>
+</dl>
+<h2>Sessions ({{len .Sessions}})</h2>
+<table>
+<tr><th>Signed in</th><th>Expires</th></tr>
+{{range .Sessions}}<tr><td>{{.CreatedAt.Format "2006-01-02 15:04"}}</td><td>{{.ExpiresAt.Format "2006-01-02 15:04"}}</td></tr>
+{{end}}</table>
+<h2>Chirps ({{len .Chirps}})</h2>
+{{range .Chirps}}<article>
+{{if .ContentWarning}}<p><strong>CW: {{.ContentWarning}}</strong></p>
+{{end}}<p>{{.Body}}</p>
+<small>{{.CreatedAt.Format "2006-01-02 15:04"}} &middot; {{.Visibility}}{{if .EditedAt}} &middot; edited{{end}}{{if .PublishAt}} &middot; scheduled{{end}}{{if .Sensitive}} &middot; sensitive{{end}}{{if .DeletedAt}} &middot; deleted{{end}}</small>
+</article>
+{{end}}</body>
+</html>
+`))
+
+// Write writes the archive to w as a zip.
+func Write(w io.Writer, archive Archive) error {
+	// an empty list reads better than null
+	if archive.Chirps == nil {
+		archive.Chirps = []Chirp{}
+	}
+	if archive.Sessions == nil {
+		archive.Sessions = []Session{}
+	}
+	zw := zip.NewWriter(w)
+	files := []struct {
+		name string
+		data any
+	}{
+		{"profile.json", archive.Profile},
+		{"chirpy_red.json", archive.ChirpyRed},
+		{"chirps.json", archive.Chirps},
+		{"sessions.json", archive.Sessions},
+	}
+	for _, file := range files {
+		f, err := zw.Create(file.name)
+		if err != nil {
+			return err
+		}
+		encoder := json.NewEncoder(f)
+		encoder.SetIndent("", "  ")
+		if err = encoder.Encode(file.data); err != nil {
+			return err
+		}
+	}
+	f, err := zw.Create("index.html")
+	if err != nil {
+		return err
+	}
+	if err = index.Execute(f, archive); err != nil {
+		return err
+	}
+	return zw.Close()
+}
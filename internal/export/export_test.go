@@ -0,0 +1,88 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"github.com/google/uuid"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func readZip(t *testing.T, data []byte) map[string][]byte {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("not a zip: %v\n", err)
+	}
+	files := map[string][]byte{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("failed to open %s: %v\n", f.Name, err)
+		}
+		files[f.Name], err = io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatalf("failed to read %s: %v\n", f.Name, err)
+		}
+	}
+	return files
+}
+
+func TestWrite(t *testing.T) {
+	now := time.Now().UTC()
+	archive := Archive{
+		GeneratedAt: now,
+		Profile:     Profile{ID: uuid.New(), Email: "me@example.com", Handle: "me", CreatedAt: now, UpdatedAt: now},
+		ChirpyRed:   ChirpyRed{Active: true, MaxChirpLength: 500},
+		Chirps: []Chirp{
+			{ID: uuid.New(), CreatedAt: now, UpdatedAt: now, Body: "<script>alert(1)</script>", Visibility: "public"},
+			{ID: uuid.New(), CreatedAt: now, UpdatedAt: now, Body: "second", Visibility: "private", DeletedAt: &now},
+		},
+		Sessions: []Session{{CreatedAt: now, ExpiresAt: now.Add(time.Hour)}},
+	}
+	var buf bytes.Buffer
+	if err := Write(&buf, archive); err != nil {
+		t.Fatalf("unexpected error: %v\n", err)
+	}
+	files := readZip(t, buf.Bytes())
+	for _, name := range []string{"profile.json", "chirpy_red.json", "chirps.json", "sessions.json", "index.html"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("missing %s in the archive\n", name)
+		}
+	}
+
+	var chirps []Chirp
+	if err := json.Unmarshal(files["chirps.json"], &chirps); err != nil {
+		t.Fatalf("chirps.json is not valid JSON: %v\n", err)
+	}
+	if len(chirps) != 2 || chirps[1].DeletedAt == nil {
+		t.Errorf("unexpected chirps %+v\n", chirps)
+	}
+	var red ChirpyRed
+	if err := json.Unmarshal(files["chirpy_red.json"], &red); err != nil || !red.Active {
+		t.Errorf("unexpected chirpy_red.json %s\n", files["chirpy_red.json"])
+	}
+
+	html := string(files["index.html"])
+	if strings.Contains(html, "<script>") {
+		t.Errorf("chirp bodies should be escaped in index.html\n")
+	}
+	if !strings.Contains(html, "me@example.com") || !strings.Contains(html, "deleted") {
+		t.Errorf("index.html is missing data:\n%s\n", html)
+	}
+}
+
+func TestWriteEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, Archive{GeneratedAt: time.Now()}); err != nil {
+		t.Fatalf("unexpected error: %v\n", err)
+	}
+	files := readZip(t, buf.Bytes())
+	if strings.TrimSpace(string(files["chirps.json"])) != "[]" {
+		t.Errorf("unexpected chirps.json %s\n", files["chirps.json"])
+	}
+}
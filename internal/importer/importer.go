@@ -0,0 +1,152 @@
+// Package importer reads chirps to import from an export file. It only
+// parses; validating and saving the chirps is up to the caller.
+package importer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+)
+
+type Format string
+
+const (
+	// FormatJSONL is one {"body": "...", "created_at": "..."} object per
+	// line, with created_at in RFC 3339 and optional.
+	FormatJSONL Format = "jsonl"
+	// FormatTwitter is the tweets.js file from a Twitter archive.
+	FormatTwitter Format = "twitter"
+)
+
+var ErrUnknownFormat = errors.New("format should be jsonl or twitter")
+
+// Record is one chirp read from a file. Line is the line number for JSONL
+// and the position in the archive for Twitter, both counted from 1.
+// CreatedAt is in the local zone, like every timestamp the server
+// stores, whatever offset the file used. Err is set when the
+// entry could not be read; the other entries are still returned.
+type Record struct {
+	Line      int
+	Body      string
+	CreatedAt time.Time
+	Err       error
+}
+
+func ParseFormat(s string) (Format, error) {
+	switch Format(s) {
+	case FormatJSONL, FormatTwitter:
+		return Format(s), nil
+	}
+	return "", ErrUnknownFormat
+}
+
+// Detect guesses the format of a file. Twitter archives are a JavaScript
+// assignment of one big array, which a JSONL file never starts with.
+func Detect(data []byte) Format {
+	trimmed := bytes.TrimSpace(data)
+	if bytes.HasPrefix(trimmed, []byte("window.YTD.")) || bytes.HasPrefix(trimmed, []byte("[")) {
+		return FormatTwitter
+	}
+	return FormatJSONL
+}
+
+// Parse reads every record in data. It only fails when the file as a whole
+// cannot be read; problems with single entries end up in Record.Err.
+func Parse(format Format, data []byte) ([]Record, error) {
+	switch format {
+	case FormatJSONL:
+		return parseJSONL(data), nil
+	case FormatTwitter:
+		return parseTwitter(data)
+	}
+	return nil, ErrUnknownFormat
+}
+
+type jsonlChirp struct {
+	Body      *string    `json:"body"`
+	CreatedAt *time.Time `json:"created_at"`
+}
+
+func parseJSONL(data []byte) []Record {
+	var records []Record
+	for i, line := range bytes.Split(data, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		record := Record{Line: i + 1}
+		var chirp jsonlChirp
+		if err := json.Unmarshal(line, &chirp); err != nil {
+			record.Err = fmt.Errorf("invalid JSON: %w", err)
+		} else if chirp.Body == nil {
+			record.Err = errors.New("missing body")
+		} else {
+			record.Body = *chirp.Body
+			if chirp.CreatedAt != nil {
+				record.CreatedAt = chirp.CreatedAt.In(time.Local)
+			}
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+type tweet struct {
+	FullText  string `json:"full_text"`
+	Text      string `json:"text"`
+	CreatedAt string `json:"created_at"`
+}
+
+// Archives from 2019 on wrap every tweet in {"tweet": {...}}; older ones
+// do not.
+type tweetEntry struct {
+	Tweet *tweet `json:"tweet"`
+	tweet
+}
+
+func parseTwitter(data []byte) ([]Record, error) {
+	// skip the `window.YTD.tweets.part0 = ` in front of the array
+	start := bytes.IndexByte(data, '[')
+	if start < 0 {
+		return nil, errors.New("not a Twitter archive: no array of tweets found")
+	}
+	var entries []json.RawMessage
+	if err := json.Unmarshal(data[start:], &entries); err != nil {
+		return nil, fmt.Errorf("not a Twitter archive: %w", err)
+	}
+	records := make([]Record, 0, len(entries))
+	for i, raw := range entries {
+		record := Record{Line: i + 1}
+		var entry tweetEntry
+		if err := json.Unmarshal(raw, &entry); err != nil {
+			record.Err = fmt.Errorf("invalid tweet: %w", err)
+			records = append(records, record)
+			continue
+		}
+		t := entry.tweet
+		if entry.Tweet != nil {
+			t = *entry.Tweet
+		}
+		text := t.FullText
+		if text == "" {
+			text = t.Text
+		}
+		// the archive keeps HTML entities such as &amp; in the text
+		record.Body = html.UnescapeString(text)
+		createdAt, err := time.Parse(time.RubyDate, t.CreatedAt)
+		switch {
+		case err != nil:
+			record.Err = fmt.Errorf("invalid created_at %q", t.CreatedAt)
+		case strings.HasPrefix(record.Body, "RT @"):
+			record.Err = errors.New("retweets are not imported")
+		default:
+			record.CreatedAt = createdAt.In(time.Local)
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
@@ -0,0 +1,98 @@
+package importer
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseJSONL(t *testing.T) {
+	data := []byte(`{"body": "hello", "created_at": "2020-05-01T10:00:00Z"}
+
+{"body": "no date"}
+not json
+{"created_at": "2020-05-01T10:00:00Z"}
+`)
+	records, err := Parse(FormatJSONL, data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v\n", err)
+	}
+	if len(records) != 4 {
+		t.Fatalf("expected 4 records, got %d\n", len(records))
+	}
+	want := time.Date(2020, 5, 1, 10, 0, 0, 0, time.UTC)
+	if records[0].Body != "hello" || !records[0].CreatedAt.Equal(want) || records[0].Err != nil {
+		t.Errorf("unexpected first record %+v\n", records[0])
+	}
+	if records[1].Line != 3 || !records[1].CreatedAt.IsZero() || records[1].Err != nil {
+		t.Errorf("unexpected record without a date %+v\n", records[1])
+	}
+	if records[2].Line != 4 || records[2].Err == nil {
+		t.Errorf("expected line 4 to be rejected, got %+v\n", records[2])
+	}
+	if records[3].Err == nil {
+		t.Errorf("expected a record without a body to be rejected\n")
+	}
+}
+
+func TestParseNormalizesToLocal(t *testing.T) {
+	records, err := Parse(FormatJSONL, []byte(`{"body": "hi", "created_at": "2020-05-01T10:00:00+02:00"}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v\n", err)
+	}
+	got := records[0].CreatedAt
+	if got.Location() != time.Local || !got.Equal(time.Date(2020, 5, 1, 8, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected 08:00 UTC in the local zone, got %v\n", got)
+	}
+	records, err = Parse(FormatTwitter, []byte(`[{"full_text": "hi", "created_at": "Wed Oct 10 20:19:24 -0500 2018"}]`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v\n", err)
+	}
+	got = records[0].CreatedAt
+	if got.Location() != time.Local || !got.Equal(time.Date(2018, 10, 11, 1, 19, 24, 0, time.UTC)) {
+		t.Errorf("expected 01:19 UTC on the 11th in the local zone, got %v\n", got)
+	}
+}
+
+func TestParseTwitter(t *testing.T) {
+	data := []byte(`window.YTD.tweets.part0 = [
+  {"tweet": {"full_text": "fish &amp; chips", "created_at": "Wed Oct 10 20:19:24 +0000 2018"}},
+  {"tweet": {"full_text": "RT @someone: hi", "created_at": "Wed Oct 10 20:19:24 +0000 2018"}},
+  {"full_text": "old style", "created_at": "Thu Jan 01 00:00:00 +0000 2015"},
+  {"tweet": {"full_text": "bad date", "created_at": "yesterday"}}
+]`)
+	if Detect(data) != FormatTwitter {
+		t.Fatalf("expected the archive to be detected as twitter\n")
+	}
+	records, err := Parse(FormatTwitter, data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v\n", err)
+	}
+	if len(records) != 4 {
+		t.Fatalf("expected 4 records, got %d\n", len(records))
+	}
+	want := time.Date(2018, 10, 10, 20, 19, 24, 0, time.UTC)
+	if records[0].Body != "fish & chips" || !records[0].CreatedAt.Equal(want) || records[0].Err != nil {
+		t.Errorf("unexpected first record %+v\n", records[0])
+	}
+	if records[1].Err == nil {
+		t.Errorf("expected retweets to be rejected\n")
+	}
+	if records[2].Body != "old style" || records[2].Err != nil {
+		t.Errorf("unexpected old style record %+v\n", records[2])
+	}
+	if records[3].Line != 4 || records[3].Err == nil {
+		t.Errorf("expected a bad date to be rejected, got %+v\n", records[3])
+	}
+}
+
+func TestParseTwitterRejectsGarbage(t *testing.T) {
+	if _, err := Parse(FormatTwitter, []byte("window.YTD.tweets.part0 = {")); err == nil {
+		t.Errorf("expected an error for a broken archive\n")
+	}
+}
+
+func TestDetectJSONL(t *testing.T) {
+	if Detect([]byte(`{"body": "hi"}`)) != FormatJSONL {
+		t.Errorf("expected JSONL to be detected\n")
+	}
+}
@@ -0,0 +1,141 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+)
+
+const (
+	MaxImageSize  = 5 << 20
+	MaxDimension  = 8192
+	MaxPixels     = 25_000_000 // MaxDimension squared would still be 64MP
+	ThumbnailSize = 320
+	jpegQuality   = 90
+)
+
+var (
+	ErrUnsupportedType = errors.New("only JPEG and PNG images are supported")
+	ErrTooLarge        = errors.New("image is too large")
+)
+
+// Image is an uploaded image after it has been decoded and encoded again.
+// Re-encoding keeps only the pixels, so EXIF and any other metadata in the
+// original upload never reach storage.
+type Image struct {
+	ContentType string
+	Width       int
+	Height      int
+	Data        []byte
+	Thumbnail   []byte
+}
+
+// Process validates an upload by sniffing its content rather than trusting
+// the declared type, then re-encodes it and renders a thumbnail that fits
+// in a ThumbnailSize square.
+func Process(data []byte) (Image, error) {
+	if len(data) > MaxImageSize {
+		return Image{}, ErrTooLarge
+	}
+	contentType := http.DetectContentType(data)
+	if Extension(contentType) == "" {
+		return Image{}, ErrUnsupportedType
+	}
+	// check the dimensions before decoding so a tiny file cannot make
+	// us allocate a huge canvas
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || "image/"+format != contentType {
+		return Image{}, ErrUnsupportedType
+	}
+	if config.Width > MaxDimension || config.Height > MaxDimension || config.Width*config.Height > MaxPixels {
+		return Image{}, ErrTooLarge
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return Image{}, ErrUnsupportedType
+	}
+	bounds := img.Bounds()
+	processed := Image{
+		ContentType: contentType,
+		Width:       bounds.Dx(),
+		Height:      bounds.Dy(),
+	}
+	if processed.Data, err = encode(img, contentType); err != nil {
+		return Image{}, err
+	}
+	if processed.Thumbnail, err = encode(Thumbnail(img, ThumbnailSize), contentType); err != nil {
+		return Image{}, err
+	}
+	return processed, nil
+}
+
+// Extension gives the file extension images of a supported content type
+// are stored with, or "" for types that are not supported.
+func Extension(contentType string) string {
+	switch contentType {
+	case "image/jpeg":
+		return ".jpg"
+	case "image/png":
+		return ".png"
+	}
+	return ""
+}
+
+func encode(img image.Image, contentType string) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	if contentType == "image/png" {
+		err = png.Encode(&buf, img)
+	} else {
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality})
+	}
+	return buf.Bytes(), err
+}
+
+// Thumbnail scales img down to fit in a size by size square, keeping its
+// aspect ratio. Every thumbnail pixel is the average of the source pixels
+// it covers. Images that already fit are returned unchanged.
+func Thumbnail(img image.Image, size int) image.Image {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w <= size && h <= size {
+		return img
+	}
+	tw, th := size, size
+	if w > h {
+		th = max(1, h*size/w)
+	} else {
+		tw = max(1, w*size/h)
+	}
+	thumb := image.NewNRGBA(image.Rect(0, 0, tw, th))
+	for ty := 0; ty < th; ty++ {
+		y0 := bounds.Min.Y + ty*h/th
+		y1 := max(y0+1, bounds.Min.Y+(ty+1)*h/th)
+		for tx := 0; tx < tw; tx++ {
+			x0 := bounds.Min.X + tx*w/tw
+			x1 := max(x0+1, bounds.Min.X+(tx+1)*w/tw)
+			var r, g, b, a, n uint64
+			for y := y0; y < y1; y++ {
+				for x := x0; x < x1; x++ {
+					c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+					r += uint64(c.R)
+					g += uint64(c.G)
+					b += uint64(c.B)
+					a += uint64(c.A)
+					n++
+				}
+			}
+			thumb.SetNRGBA(tx, ty, color.NRGBA{
+				R: uint8(r / n),
+				G: uint8(g / n),
+				B: uint8(b / n),
+				A: uint8(a / n),
+			})
+		}
+	}
+	return thumb
+}
@@ -0,0 +1,103 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func solidImage(w, h int, c color.Color) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+func TestProcessPNG(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, solidImage(800, 400, color.NRGBA{R: 200, A: 255})); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	processed, err := Process(buf.Bytes())
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	if processed.ContentType != "image/png" || processed.Width != 800 || processed.Height != 400 {
+		t.Errorf("unexpected image: %s %dx%d\n", processed.ContentType, processed.Width, processed.Height)
+	}
+	thumb, err := png.Decode(bytes.NewReader(processed.Thumbnail))
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	if thumb.Bounds().Dx() != ThumbnailSize || thumb.Bounds().Dy() != ThumbnailSize/2 {
+		t.Errorf("unexpected thumbnail size: %v\n", thumb.Bounds())
+	}
+}
+
+func TestProcessStripsMetadata(t *testing.T) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, solidImage(16, 16, color.White), nil); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	// splice an APP1 (EXIF) segment in right after the SOI marker
+	exif := []byte{0xFF, 0xE1, 0x00, 0x0E, 'E', 'x', 'i', 'f', 0, 0, 'c', 'h', 'i', 'r', 'p', 'y'}
+	data := append(append([]byte{0xFF, 0xD8}, exif...), buf.Bytes()[2:]...)
+	processed, err := Process(data)
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	if bytes.Contains(processed.Data, []byte("Exif")) {
+		t.Errorf("EXIF segment survived re-encoding\n")
+	}
+}
+
+func TestProcessRejects(t *testing.T) {
+	testCases := map[string][]byte{
+		"text":      []byte("definitely not an image"),
+		"gif":       []byte("GIF89a\x01\x00\x01\x00\x00\x00\x00;"),
+		"truncated": {0x89, 'P', 'N', 'G', '\r', '\n', 0x1A, '\n'},
+	}
+	for name, data := range testCases {
+		if _, err := Process(data); err != ErrUnsupportedType {
+			t.Errorf("%s: expected ErrUnsupportedType, got %v\n", name, err)
+		}
+	}
+	if _, err := Process(make([]byte, MaxImageSize+1)); err != ErrTooLarge {
+		t.Errorf("expected ErrTooLarge, got %v\n", err)
+	}
+}
+
+func TestProcessRejectsTooManyPixels(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, solidImage(1, 1, color.White)); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	// claim 6000x6000 in the IHDR chunk; each side is under MaxDimension
+	// but together they are over MaxPixels
+	data := buf.Bytes()
+	binary.BigEndian.PutUint32(data[16:20], 6000)
+	binary.BigEndian.PutUint32(data[20:24], 6000)
+	binary.BigEndian.PutUint32(data[29:33], crc32.ChecksumIEEE(data[12:29]))
+	if _, err := Process(data); err != ErrTooLarge {
+		t.Errorf("expected ErrTooLarge, got %v\n", err)
+	}
+}
+
+func TestThumbnailAverages(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	img.Set(0, 0, color.NRGBA{A: 255})
+	img.Set(1, 0, color.NRGBA{R: 255, G: 255, B: 255, A: 255})
+	thumb := Thumbnail(img, 1)
+	c := color.NRGBAModel.Convert(thumb.At(0, 0)).(color.NRGBA)
+	if thumb.Bounds().Dx() != 1 || c.R != 127 {
+		t.Errorf("unexpected thumbnail pixel: %v\n", c)
+	}
+}
@@ -0,0 +1,64 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"strconv"
+	"time"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+// Cursor points at the last row of a page. Its encoded form is opaque to
+// clients; they only ever pass back what they received as next_cursor.
+// Rank is only set when paging through ranked search results.
+type Cursor struct {
+	Rank float32   `json:"r,omitempty"`
+	Time time.Time `json:"t"`
+	ID   uuid.UUID `json:"id"`
+}
+
+func (c Cursor) Encode() string {
+	data, err := json.Marshal(c)
+	if err != nil {
+		// a struct of a number, a time and a uuid always marshals
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func DecodeCursor(s string) (Cursor, error) {
+	var c Cursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, fmt.Errorf("invalid cursor")
+	}
+	if err = json.Unmarshal(data, &c); err != nil {
+		return c, fmt.Errorf("invalid cursor")
+	}
+	if c.ID == uuid.Nil || c.Time.IsZero() {
+		return c, fmt.Errorf("invalid cursor")
+	}
+	return c, nil
+}
+
+// ParseLimit reads the `limit` query parameter. An empty value gives
+// DefaultLimit and anything above MaxLimit is clamped.
+func ParseLimit(s string) (int32, error) {
+	if s == "" {
+		return DefaultLimit, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("limit should be a positive integer")
+	}
+	if n > MaxLimit {
+		n = MaxLimit
+	}
+	return int32(n), nil
+}
@@ -0,0 +1,68 @@
+package pagination
+
+import (
+	"github.com/google/uuid"
+	"testing"
+	"time"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	for i := 0; i < 10; i++ {
+		c := Cursor{
+			Rank: float32(i) / 7,
+			Time: time.Now().Add(time.Duration(-i) * time.Hour).UTC(),
+			ID:   uuid.New(),
+		}
+		decoded, err := DecodeCursor(c.Encode())
+		if err != nil {
+			t.Errorf("%v\n", err)
+		}
+		if decoded.Rank != c.Rank || !decoded.Time.Equal(c.Time) || decoded.ID != c.ID {
+			t.Errorf("cursor does not match: %v vs %v\n", decoded, c)
+		}
+	}
+}
+
+func TestDecodeInvalidCursor(t *testing.T) {
+	testCases := []string{
+		"",
+		"not base64!",
+		"bm90IGpzb24",
+		Cursor{}.Encode(),
+	}
+	for _, testCase := range testCases {
+		if _, err := DecodeCursor(testCase); err == nil {
+			t.Errorf("expected an error for cursor `%s`\n", testCase)
+		}
+	}
+}
+
+func TestParseLimit(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected int32
+		wantErr  bool
+	}{
+		{input: "", expected: DefaultLimit},
+		{input: "5", expected: 5},
+		{input: "1000", expected: MaxLimit},
+		{input: "0", wantErr: true},
+		{input: "-3", wantErr: true},
+		{input: "ten", wantErr: true},
+	}
+	for _, testCase := range testCases {
+		limit, err := ParseLimit(testCase.input)
+		if testCase.wantErr {
+			if err == nil {
+				t.Errorf("expected an error for limit `%s`\n", testCase.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v\n", err)
+		}
+		if limit != testCase.expected {
+			t.Errorf("limit does not match for `%s`: %d vs %d\n", testCase.input, limit, testCase.expected)
+		}
+	}
+}
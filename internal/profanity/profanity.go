@@ -0,0 +1,152 @@
+// Package profanity matches chirp bodies against word lists. Each list
+// belongs to a locale and says what happens to a chirp that uses one of
+// its terms.
+package profanity
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+type Strategy string
+
+const (
+	// Mask replaces the term with asterisks.
+	Mask Strategy = "mask"
+	// Reject refuses the chirp.
+	Reject Strategy = "reject"
+	// Flag keeps the chirp as written but marks it sensitive.
+	Flag Strategy = "flag"
+
+	mask = "****"
+)
+
+func ParseStrategy(s string) (Strategy, error) {
+	switch strategy := Strategy(s); strategy {
+	case Mask, Reject, Flag:
+		return strategy, nil
+	}
+	return "", fmt.Errorf("strategy should be one of %s, %s or %s", Mask, Reject, Flag)
+}
+
+// severity orders strategies for a term that is on more than one list;
+// the strictest one wins.
+func (s Strategy) severity() int {
+	switch s {
+	case Reject:
+		return 3
+	case Flag:
+		return 2
+	}
+	return 1
+}
+
+type List struct {
+	Name     string   `json:"name"`
+	Locale   string   `json:"locale"`
+	Strategy Strategy `json:"strategy"`
+	Terms    []string `json:"terms"`
+}
+
+// NormalizeTerm is how terms are stored and compared.
+func NormalizeTerm(term string) string {
+	return strings.ToLower(strings.TrimSpace(term))
+}
+
+// ValidTerm reports whether a normalized term can match at all. Bodies are
+// matched word by word, so a term cannot contain spaces.
+func ValidTerm(term string) bool {
+	return term != "" && !strings.ContainsFunc(term, unicode.IsSpace)
+}
+
+// Result is what a filter made of a chirp body.
+type Result struct {
+	// Body has the masked terms replaced.
+	Body string
+	// Rejected lists the terms that refuse the chirp, in the order they
+	// first appear.
+	Rejected []string
+	Flagged  bool
+}
+
+// Filter is an immutable snapshot of the loaded lists. Reloading builds a
+// new one, so it is safe to share between goroutines.
+type Filter struct {
+	locales []string
+	terms   map[string]Strategy
+}
+
+// New builds a filter from every list in locales. No locales means every
+// list applies.
+func New(lists []List, locales []string) *Filter {
+	f := &Filter{locales: locales, terms: map[string]Strategy{}}
+	for _, list := range lists {
+		if !f.Enabled(list.Locale) {
+			continue
+		}
+		for _, term := range list.Terms {
+			term = NormalizeTerm(term)
+			if current, ok := f.terms[term]; !ok || list.Strategy.severity() > current.severity() {
+				f.terms[term] = list.Strategy
+			}
+		}
+	}
+	return f
+}
+
+// Enabled reports whether the lists for locale are applied.
+func (f *Filter) Enabled(locale string) bool {
+	return len(f.locales) == 0 || slices.Contains(f.locales, locale)
+}
+
+// Check matches body word by word. Words are split on spaces only, so a
+// term followed by punctuation is left alone.
+func (f *Filter) Check(body string) Result {
+	var result Result
+	words := strings.Split(body, " ")
+	for i, word := range words {
+		term := strings.ToLower(word)
+		switch f.terms[term] {
+		case Mask:
+			words[i] = mask
+		case Reject:
+			if !slices.Contains(result.Rejected, term) {
+				result.Rejected = append(result.Rejected, term)
+			}
+		case Flag:
+			result.Flagged = true
+		}
+	}
+	result.Body = strings.Join(words, " ")
+	return result
+}
+
+// ReadFile reads lists from a JSON file holding an array of lists.
+func ReadFile(path string) ([]List, error) {
+	dat, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var lists []List
+	if err = json.Unmarshal(dat, &lists); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for i, list := range lists {
+		if list.Name == "" {
+			return nil, fmt.Errorf("%s: list %d has no name", path, i+1)
+		}
+		if _, err = ParseStrategy(string(list.Strategy)); err != nil {
+			return nil, fmt.Errorf("%s: list %s: %w", path, list.Name, err)
+		}
+		for _, term := range list.Terms {
+			if !ValidTerm(NormalizeTerm(term)) {
+				return nil, fmt.Errorf("%s: list %s: invalid term %q", path, list.Name, term)
+			}
+		}
+	}
+	return lists, nil
+}
@@ -0,0 +1,83 @@
+package profanity
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	f := New([]List{
+		{Name: "default", Locale: "en", Strategy: Mask, Terms: []string{"kerfuffle", "sharbert", "fornax"}},
+		{Name: "slurs", Locale: "en", Strategy: Reject, Terms: []string{"blorp", "Fornax"}},
+		{Name: "nsfw", Locale: "en", Strategy: Flag, Terms: []string{"spicy"}},
+		{Name: "spanish", Locale: "es", Strategy: Mask, Terms: []string{"caramba"}},
+	}, []string{"en"})
+	testCases := []struct {
+		body     string
+		cleaned  string
+		rejected []string
+		flagged  bool
+	}{
+		{body: "I had something interesting for breakfast", cleaned: "I had something interesting for breakfast"},
+		{body: "I hear Mastodon is better than Chirpy. sharbert I need to migrate", cleaned: "I hear Mastodon is better than Chirpy. **** I need to migrate"},
+		{body: "I really need a kerfuffle to go to bed sooner, Fornax !", cleaned: "I really need a **** to go to bed sooner, Fornax !", rejected: []string{"fornax"}},
+		// punctuation keeps a word from matching
+		{body: "Sharbert!", cleaned: "Sharbert!"},
+		{body: "blorp BLORP", cleaned: "blorp BLORP", rejected: []string{"blorp"}},
+		{body: "a spicy take", cleaned: "a spicy take", flagged: true},
+		// not an enabled locale
+		{body: "ay caramba", cleaned: "ay caramba"},
+	}
+	for _, testCase := range testCases {
+		result := f.Check(testCase.body)
+		if result.Body != testCase.cleaned {
+			t.Errorf("cleaned body for %q does not match: %q vs %q\n", testCase.body, result.Body, testCase.cleaned)
+		}
+		if strings.Join(result.Rejected, ",") != strings.Join(testCase.rejected, ",") {
+			t.Errorf("rejected terms for %q do not match: %v vs %v\n", testCase.body, result.Rejected, testCase.rejected)
+		}
+		if result.Flagged != testCase.flagged {
+			t.Errorf("flagged for %q does not match: %v vs %v\n", testCase.body, result.Flagged, testCase.flagged)
+		}
+	}
+}
+
+func TestAllLocales(t *testing.T) {
+	f := New([]List{{Name: "spanish", Locale: "es", Strategy: Mask, Terms: []string{"caramba"}}}, nil)
+	if got := f.Check("ay caramba").Body; got != "ay ****" {
+		t.Errorf("expected every locale to apply without a locale list, got %q\n", got)
+	}
+	if !f.Enabled("xx") {
+		t.Errorf("expected every locale to be enabled\n")
+	}
+}
+
+func TestReadFile(t *testing.T) {
+	dir := t.TempDir()
+	testCases := []struct {
+		contents string
+		valid    bool
+	}{
+		{contents: `[{"name": "default", "locale": "en", "strategy": "mask", "terms": ["kerfuffle"]}]`, valid: true},
+		{contents: `[]`, valid: true},
+		{contents: `[{"name": "default", "locale": "en", "strategy": "censor", "terms": []}]`},
+		{contents: `[{"locale": "en", "strategy": "mask", "terms": []}]`},
+		{contents: `[{"name": "default", "locale": "en", "strategy": "mask", "terms": ["two words"]}]`},
+		{contents: `{"name": "default"}`},
+	}
+	for i, testCase := range testCases {
+		path := filepath.Join(dir, "lists.json")
+		if err := os.WriteFile(path, []byte(testCase.contents), 0o600); err != nil {
+			t.Fatalf("%v\n", err)
+		}
+		_, err := ReadFile(path)
+		if (err == nil) != testCase.valid {
+			t.Errorf("case %d: unexpected error %v\n", i, err)
+		}
+	}
+	if _, err := ReadFile(filepath.Join(dir, "missing.json")); err == nil {
+		t.Errorf("expected an error for a missing file\n")
+	}
+}
@@ -0,0 +1,71 @@
+package ratelimit
+
+import (
+	"github.com/google/uuid"
+	"sync"
+	"time"
+)
+
+// sweepThreshold is how many buckets a Limiter holds before it drops the
+// ones that have refilled completely, which carry no information.
+const sweepThreshold = 10000
+
+// Limiter is an in-memory token bucket per user. The rate is passed on
+// every call rather than fixed per Limiter, so users on different plans
+// share one Limiter.
+type Limiter struct {
+	mu      sync.Mutex
+	buckets map[uuid.UUID]*bucket
+	now     func() time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func New() *Limiter {
+	return &Limiter{
+		buckets: map[uuid.UUID]*bucket{},
+		now:     time.Now,
+	}
+}
+
+// Allow reports whether key may act now, and uses up one action if so.
+// The bucket holds up to perMinute actions and refills at perMinute a
+// minute.
+func (l *Limiter) Allow(key uuid.UUID, perMinute int) bool {
+	return l.AllowN(key, perMinute, 1)
+}
+
+// AllowN is Allow for n actions at once. Either all n are used up or
+// none are, so n above perMinute is never allowed.
+func (l *Limiter) AllowN(key uuid.UUID, perMinute int, n int) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	capacity := float64(perMinute)
+	b, ok := l.buckets[key]
+	if !ok {
+		if len(l.buckets) >= sweepThreshold {
+			l.sweep(now, capacity)
+		}
+		b = &bucket{tokens: capacity, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = min(capacity, b.tokens+now.Sub(b.last).Minutes()*capacity)
+	b.last = now
+	if b.tokens < float64(n) {
+		return false
+	}
+	b.tokens -= float64(n)
+	return true
+}
+
+func (l *Limiter) sweep(now time.Time, capacity float64) {
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Minutes()*capacity >= capacity {
+			delete(l.buckets, key)
+		}
+	}
+}
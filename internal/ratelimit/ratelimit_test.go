@@ -0,0 +1,82 @@
+package ratelimit
+
+import (
+	"github.com/google/uuid"
+	"testing"
+	"time"
+)
+
+func TestAllowBurstThenRefill(t *testing.T) {
+	now := time.Now()
+	l := New()
+	l.now = func() time.Time { return now }
+	user := uuid.New()
+	for i := 0; i < 3; i++ {
+		if !l.Allow(user, 3) {
+			t.Fatalf("action %d should be allowed\n", i)
+		}
+	}
+	if l.Allow(user, 3) {
+		t.Errorf("fourth action in the same instant should be limited\n")
+	}
+	now = now.Add(20 * time.Second)
+	if !l.Allow(user, 3) {
+		t.Errorf("a token should have refilled after 20 seconds\n")
+	}
+	if l.Allow(user, 3) {
+		t.Errorf("only one token should have refilled\n")
+	}
+}
+
+func TestAllowNTakesAllOrNothing(t *testing.T) {
+	now := time.Now()
+	l := New()
+	l.now = func() time.Time { return now }
+	user := uuid.New()
+	if l.AllowN(user, 5, 6) {
+		t.Fatalf("more actions than the bucket holds should be limited\n")
+	}
+	if !l.AllowN(user, 5, 4) {
+		t.Fatalf("4 of 5 actions should be allowed\n")
+	}
+	if l.AllowN(user, 5, 2) {
+		t.Errorf("2 actions should be limited with 1 left\n")
+	}
+	if !l.Allow(user, 5) {
+		t.Errorf("the last action should still be allowed\n")
+	}
+}
+
+func TestAllowIsPerUser(t *testing.T) {
+	l := New()
+	first, second := uuid.New(), uuid.New()
+	if !l.Allow(first, 1) {
+		t.Fatalf("first user should be allowed\n")
+	}
+	if l.Allow(first, 1) {
+		t.Errorf("first user should be limited\n")
+	}
+	if !l.Allow(second, 1) {
+		t.Errorf("second user should not be limited by the first\n")
+	}
+}
+
+func TestHigherRateAllowsMore(t *testing.T) {
+	l := New()
+	free, red := uuid.New(), uuid.New()
+	allowed := map[uuid.UUID]int{}
+	for i := 0; i < 100; i++ {
+		for _, user := range []uuid.UUID{free, red} {
+			rate := 10
+			if user == red {
+				rate = 60
+			}
+			if l.Allow(user, rate) {
+				allowed[user]++
+			}
+		}
+	}
+	if allowed[free] != 10 || allowed[red] != 60 {
+		t.Errorf("unexpected allowances: free %d, red %d\n", allowed[free], allowed[red])
+	}
+}
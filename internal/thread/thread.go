@@ -0,0 +1,42 @@
+// Package thread arranges the chirps of a conversation into a tree.
+package thread
+
+import (
+	"github.com/google/uuid"
+	"github.com/uncomfyhalomacro/chirpy/internal/database"
+)
+
+type Node struct {
+	Chirp   database.Chirp
+	Replies []Node
+}
+
+// Hidden reports whether a chirp is shown as a tombstone in a thread. A
+// soft-deleted chirp looks the same as one the purger already tombstoned,
+// so nothing of it shows until it is restored, but its replies still do.
+func Hidden(chirp database.Chirp) bool {
+	return chirp.IsTombstone || chirp.DeletedAt.Valid
+}
+
+// Build nests replies under the chirp parentID. Siblings keep the order
+// they have in replies, and replies whose parent is missing are left out.
+func Build(parentID uuid.UUID, replies []database.Chirp) []Node {
+	children := map[uuid.UUID][]database.Chirp{}
+	for _, reply := range replies {
+		if reply.ParentChirpID.Valid {
+			children[reply.ParentChirpID.UUID] = append(children[reply.ParentChirpID.UUID], reply)
+		}
+	}
+	return build(parentID, children)
+}
+
+func build(parentID uuid.UUID, children map[uuid.UUID][]database.Chirp) []Node {
+	nodes := []Node{}
+	for _, chirp := range children[parentID] {
+		nodes = append(nodes, Node{
+			Chirp:   chirp,
+			Replies: build(chirp.ID, children),
+		})
+	}
+	return nodes
+}
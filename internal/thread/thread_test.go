@@ -0,0 +1,61 @@
+package thread
+
+import (
+	"database/sql"
+	"github.com/google/uuid"
+	"github.com/uncomfyhalomacro/chirpy/internal/database"
+	"testing"
+	"time"
+)
+
+func reply(parent database.Chirp) database.Chirp {
+	return database.Chirp{
+		ID:            uuid.New(),
+		ParentChirpID: uuid.NullUUID{UUID: parent.ID, Valid: true},
+	}
+}
+
+func TestBuildKeepsRepliesOfDeletedChirps(t *testing.T) {
+	root := database.Chirp{ID: uuid.New()}
+	middle := reply(root)
+	middle.DeletedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	first := reply(middle)
+	second := reply(middle)
+	nested := reply(first)
+	sibling := reply(root)
+
+	nodes := Build(root.ID, []database.Chirp{middle, sibling, first, second, nested})
+	if len(nodes) != 2 || nodes[0].Chirp.ID != middle.ID || nodes[1].Chirp.ID != sibling.ID {
+		t.Fatalf("unexpected direct replies %+v\n", nodes)
+	}
+	if !Hidden(nodes[0].Chirp) || Hidden(nodes[1].Chirp) {
+		t.Errorf("only the deleted chirp should be hidden\n")
+	}
+	replies := nodes[0].Replies
+	if len(replies) != 2 || replies[0].Chirp.ID != first.ID || replies[1].Chirp.ID != second.ID {
+		t.Fatalf("replies of the deleted chirp are missing: %+v\n", replies)
+	}
+	if len(replies[0].Replies) != 1 || replies[0].Replies[0].Chirp.ID != nested.ID {
+		t.Errorf("nested reply is missing: %+v\n", replies[0].Replies)
+	}
+	if len(nodes[1].Replies) != 0 {
+		t.Errorf("expected no replies, got %+v\n", nodes[1].Replies)
+	}
+}
+
+func TestBuildDropsOrphans(t *testing.T) {
+	root := database.Chirp{ID: uuid.New()}
+	orphan := reply(database.Chirp{ID: uuid.New()})
+	if nodes := Build(root.ID, []database.Chirp{orphan}); len(nodes) != 0 {
+		t.Errorf("expected no nodes, got %+v\n", nodes)
+	}
+}
+
+func TestHidden(t *testing.T) {
+	if Hidden(database.Chirp{}) {
+		t.Errorf("a plain chirp is not hidden\n")
+	}
+	if !Hidden(database.Chirp{IsTombstone: true}) {
+		t.Errorf("a tombstone is hidden\n")
+	}
+}
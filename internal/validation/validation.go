@@ -0,0 +1,156 @@
+package validation
+
+import (
+	"fmt"
+	"github.com/uncomfyhalomacro/chirpy/internal/entities"
+	"golang.org/x/text/unicode/norm"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	CodeBlank        = "blank"
+	CodeTooLong      = "too_long"
+	CodeTooManyLinks = "too_many_links"
+	CodeProfanity    = "profanity"
+
+	DefaultMaxLinks = 3
+)
+
+// Violation is one machine-readable reason a chirp was rejected.
+type Violation struct {
+	Code    string `json:"code"`
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Input is the chirp being validated. Validators that normalize the body
+// rewrite Body in place for the ones after them.
+type Input struct {
+	Body string
+	// MaxLength is the longest body the author may post, in characters.
+	MaxLength int
+	// LinkLength is how many characters a link counts as, because links
+	// are replaced by short links of that length when the chirp is
+	// saved. Zero counts links as they are.
+	LinkLength int
+}
+
+type Validator interface {
+	Validate(in *Input) []Violation
+}
+
+// Pipeline runs validators in order. Every validator runs, so a rejected
+// chirp reports all of its violations at once.
+type Pipeline []Validator
+
+func (p Pipeline) Run(in *Input) []Violation {
+	violations := []Violation{}
+	for _, validator := range p {
+		violations = append(violations, validator.Validate(in)...)
+	}
+	return violations
+}
+
+// DefaultNames is the pipeline used when none is configured. Normalizers
+// come first so the checks see the body that will be stored.
+var DefaultNames = []string{"nfc", "control", "blank", "length", "links"}
+
+// New builds a pipeline from validator names, in the order given.
+// maxLinks configures the "links" validator.
+func New(names []string, maxLinks int) (Pipeline, error) {
+	pipeline := Pipeline{}
+	for _, name := range names {
+		switch strings.TrimSpace(name) {
+		case "nfc":
+			pipeline = append(pipeline, NFC{})
+		case "control":
+			pipeline = append(pipeline, StripControl{})
+		case "blank":
+			pipeline = append(pipeline, NotBlank{})
+		case "length":
+			pipeline = append(pipeline, Length{})
+		case "links":
+			pipeline = append(pipeline, Links{Max: maxLinks})
+		case "":
+		default:
+			return nil, fmt.Errorf("unknown chirp validator %q", name)
+		}
+	}
+	return pipeline, nil
+}
+
+// NFC normalizes the body to Unicode normalization form C, so the same
+// text is always stored and counted the same way.
+type NFC struct{}
+
+func (NFC) Validate(in *Input) []Violation {
+	in.Body = norm.NFC.String(in.Body)
+	return nil
+}
+
+// StripControl removes control characters other than newlines and tabs.
+type StripControl struct{}
+
+func (StripControl) Validate(in *Input) []Violation {
+	in.Body = strings.Map(func(r rune) rune {
+		if r != '\n' && r != '\t' && unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, in.Body)
+	return nil
+}
+
+// NotBlank rejects bodies that are empty or only whitespace.
+type NotBlank struct{}
+
+func (NotBlank) Validate(in *Input) []Violation {
+	if strings.TrimSpace(in.Body) != "" {
+		return nil
+	}
+	return []Violation{{
+		Code:    CodeBlank,
+		Field:   "body",
+		Message: "Chirp cannot be blank",
+	}}
+}
+
+// Length limits the body to MaxLength characters, counted as Unicode code
+// points rather than bytes.
+type Length struct{}
+
+func (Length) Validate(in *Input) []Violation {
+	n := utf8.RuneCountInString(in.Body)
+	if in.LinkLength > 0 {
+		for _, link := range entities.Links(in.Body) {
+			n += in.LinkLength - utf8.RuneCountInString(link.URL)
+		}
+	}
+	if n <= in.MaxLength {
+		return nil
+	}
+	return []Violation{{
+		Code:    CodeTooLong,
+		Field:   "body",
+		Message: fmt.Sprintf("Chirp is too long: %d characters, the limit is %d", n, in.MaxLength),
+	}}
+}
+
+// Links limits how many links a body may contain.
+type Links struct {
+	Max int
+}
+
+func (l Links) Validate(in *Input) []Violation {
+	n := len(entities.Links(in.Body))
+	if n <= l.Max {
+		return nil
+	}
+	return []Violation{{
+		Code:    CodeTooManyLinks,
+		Field:   "body",
+		Message: fmt.Sprintf("Chirp has %d links, the limit is %d", n, l.Max),
+	}}
+}
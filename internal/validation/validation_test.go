@@ -0,0 +1,84 @@
+package validation
+
+import (
+	"strings"
+	"testing"
+)
+
+func codes(violations []Violation) []string {
+	out := []string{}
+	for _, violation := range violations {
+		out = append(out, violation.Code)
+	}
+	return out
+}
+
+func TestDefaultPipeline(t *testing.T) {
+	pipeline, err := New(DefaultNames, DefaultMaxLinks)
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	testCases := []struct {
+		body     string
+		max      int
+		expected []string
+		cleaned  string
+	}{
+		{body: "hello world", max: 140, expected: []string{}, cleaned: "hello world"},
+		{body: "   \n\t ", max: 140, expected: []string{CodeBlank}, cleaned: "   \n\t "},
+		{body: "", max: 140, expected: []string{CodeBlank}, cleaned: ""},
+		// 140 characters but 280 bytes
+		{body: strings.Repeat("é", 140), max: 140, expected: []string{}, cleaned: strings.Repeat("é", 140)},
+		{body: strings.Repeat("a", 141), max: 140, expected: []string{CodeTooLong}, cleaned: strings.Repeat("a", 141)},
+		// e followed by a combining acute accent composes to one character
+		{body: strings.Repeat("e\u0301", 140), max: 140, expected: []string{}, cleaned: strings.Repeat("\u00e9", 140)},
+		{body: "bell\a and\x00 null\nkept", max: 140, expected: []string{}, cleaned: "bell and null\nkept"},
+		{body: "\x00\x01", max: 140, expected: []string{CodeBlank}, cleaned: ""},
+		{body: "https://a.b http://c.d HTTPS://e.f https://g.h", max: 140, expected: []string{CodeTooManyLinks}},
+		{body: strings.Repeat("http://x ", 20), max: 140, expected: []string{CodeTooLong, CodeTooManyLinks}},
+	}
+	for _, testCase := range testCases {
+		in := Input{Body: testCase.body, MaxLength: testCase.max}
+		got := codes(pipeline.Run(&in))
+		if strings.Join(got, ",") != strings.Join(testCase.expected, ",") {
+			t.Errorf("violations for `%q` do not match: %v vs %v\n", testCase.body, got, testCase.expected)
+		}
+		if testCase.cleaned != "" && in.Body != testCase.cleaned {
+			t.Errorf("cleaned body for `%q` does not match: %q vs %q\n", testCase.body, in.Body, testCase.cleaned)
+		}
+	}
+}
+
+func TestNewConfiguresPipeline(t *testing.T) {
+	pipeline, err := New([]string{"length", " links "}, 0)
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	if len(pipeline) != 2 {
+		t.Fatalf("unexpected pipeline: %v\n", pipeline)
+	}
+	_, isLength := pipeline[0].(Length)
+	_, isLinks := pipeline[1].(Links)
+	if !isLength || !isLinks {
+		t.Errorf("unexpected pipeline: %v\n", pipeline)
+	}
+	in := Input{Body: "   ", MaxLength: 140}
+	if violations := pipeline.Run(&in); len(violations) != 0 {
+		t.Errorf("blank check ran although it was not configured: %v\n", violations)
+	}
+	if _, err := New([]string{"nfc", "spellcheck"}, 0); err == nil {
+		t.Errorf("expected an error for an unknown validator\n")
+	}
+}
+
+func TestLengthCountsShortLinks(t *testing.T) {
+	long := "https://example.com/" + strings.Repeat("a", 200)
+	in := Input{Body: "read " + long, MaxLength: 140, LinkLength: 30}
+	if violations := (Length{}).Validate(&in); len(violations) != 0 {
+		t.Errorf("a long link should count as its short length: %v\n", violations)
+	}
+	in = Input{Body: "read " + long, MaxLength: 140}
+	if violations := (Length{}).Validate(&in); len(violations) != 1 {
+		t.Errorf("without a link length the link should count in full\n")
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/uncomfyhalomacro/chirpy/internal/auth"
+	"github.com/uncomfyhalomacro/chirpy/internal/database"
+	"github.com/uncomfyhalomacro/chirpy/internal/pagination"
+	"log"
+	"net/http"
+	"time"
+)
+
+func (cfg *apiConfig) likeChirp(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Println("no bearer")
+		w.WriteHeader(401)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+	userID, err := cfg.validateToken(r.Context(), token)
+	if err != nil {
+		log.Printf("jwt error: %v", err)
+		w.WriteHeader(401)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+	id, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		msg := fmt.Sprintf("400 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 400)
+		return
+	}
+	chirp, err := cfg.db.GetChirp(r.Context(), database.GetChirpParams{
+		ID:       id,
+		ViewerID: uuid.NullUUID{UUID: userID, Valid: true},
+	})
+	if err != nil {
+		msg := fmt.Sprintf("404 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 404)
+		return
+	}
+	err = cfg.db.LikeChirp(r.Context(), database.LikeChirpParams{
+		UserID:    userID,
+		ChirpID:   chirp.ID,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		http.Error(w, http.StatusText(500), 500)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) unlikeChirp(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Println("no bearer")
+		w.WriteHeader(401)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+	userID, err := cfg.validateToken(r.Context(), token)
+	if err != nil {
+		log.Printf("jwt error: %v", err)
+		w.WriteHeader(401)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+	id, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		msg := fmt.Sprintf("400 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 400)
+		return
+	}
+	err = cfg.db.UnlikeChirp(r.Context(), database.UnlikeChirpParams{
+		UserID:  userID,
+		ChirpID: id,
+	})
+	if err != nil {
+		http.Error(w, http.StatusText(500), 500)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) getUserLikes(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		msg := fmt.Sprintf("400 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 400)
+		return
+	}
+	limit, err := pagination.ParseLimit(r.URL.Query().Get("limit"))
+	if err != nil {
+		msg := fmt.Sprintf("400 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 400)
+		return
+	}
+	params := database.ListLikedChirpsParams{
+		UserID:    userID,
+		ViewerID:  cfg.viewerID(r),
+		PageLimit: limit + 1,
+	}
+	if rawCursor := r.URL.Query().Get("cursor"); rawCursor != "" {
+		cursor, err := pagination.DecodeCursor(rawCursor)
+		if err != nil {
+			msg := fmt.Sprintf("400 - %s", err)
+			log.Printf("%s\n", msg)
+			http.Error(w, msg, 400)
+			return
+		}
+		params.AfterLikedAt = sql.NullTime{Time: cursor.Time, Valid: true}
+		params.AfterID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
+	}
+	rows, err := cfg.db.ListLikedChirps(r.Context(), params)
+	if err != nil {
+		msg := fmt.Sprintf("500 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	var page chirpsPage
+	if len(rows) > int(limit) {
+		rows = rows[:limit]
+		last := rows[len(rows)-1]
+		page.NextCursor = pagination.Cursor{Time: last.LikedAt, ID: last.Chirp.ID}.Encode()
+	}
+	chirps := make([]database.Chirp, 0, len(rows))
+	for _, row := range rows {
+		chirps = append(chirps, row.Chirp)
+	}
+	page.Chirps, err = cfg.loadChirpsJSON(r.Context(), cfg.viewerID(r), chirps)
+	if err != nil {
+		msg := fmt.Sprintf("500 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+
+	dat, errMarshal := json.Marshal(page)
+	if errMarshal != nil {
+		msg := fmt.Sprintf("500 - %s", errMarshal)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+	w.Write(dat)
+}
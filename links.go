@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/uncomfyhalomacro/chirpy/internal/auth"
+	"github.com/uncomfyhalomacro/chirpy/internal/database"
+	"github.com/uncomfyhalomacro/chirpy/internal/entities"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	linkCodeLength   = 8
+	linkCodeAlphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	// clicks are counted per hour
+	linkClickBucket = time.Hour
+)
+
+type returnLinkBucket struct {
+	Bucket time.Time `json:"bucket"`
+	Clicks int64     `json:"clicks"`
+}
+
+// returnLinkReferrer has an empty Referrer for clicks that came without
+// one.
+type returnLinkReferrer struct {
+	Referrer string `json:"referrer"`
+	Clicks   int64  `json:"clicks"`
+}
+
+type returnLink struct {
+	Code      string               `json:"code"`
+	URL       string               `json:"url"`
+	ShortURL  string               `json:"short_url"`
+	Clicks    int64                `json:"clicks"`
+	Timeline  []returnLinkBucket   `json:"timeline"`
+	Referrers []returnLinkReferrer `json:"referrers"`
+}
+
+func (cfg *apiConfig) shortLinkPrefix() string {
+	return cfg.publicURL + "/l/"
+}
+
+func newLinkCode() string {
+	code := make([]byte, 0, linkCodeLength)
+	buf := make([]byte, linkCodeLength*2)
+	for len(code) < linkCodeLength {
+		rand.Read(buf)
+		for _, b := range buf {
+			// drop bytes past the last full multiple of the alphabet so
+			// every character is equally likely
+			if int(b) >= 256/len(linkCodeAlphabet)*len(linkCodeAlphabet) {
+				continue
+			}
+			code = append(code, linkCodeAlphabet[int(b)%len(linkCodeAlphabet)])
+			if len(code) == linkCodeLength {
+				break
+			}
+		}
+	}
+	return string(code)
+}
+
+// saveChirpLinks replaces every URL in a chirp's body with a short link
+// and stores where each one points. Short links that are already in the
+// body, e.g. after an edit, are left alone.
+func (cfg *apiConfig) saveChirpLinks(ctx context.Context, q *database.Queries, chirp database.Chirp) (database.Chirp, error) {
+	links := entities.Links(chirp.Body)
+	if len(links) == 0 {
+		return chirp, nil
+	}
+	var body strings.Builder
+	prev := 0
+	changed := false
+	for _, found := range links {
+		if strings.HasPrefix(found.URL, cfg.shortLinkPrefix()) {
+			continue
+		}
+		link, err := q.CreateLink(ctx, database.CreateLinkParams{
+			CreatedAt: chirp.UpdatedAt,
+			Code:      newLinkCode(),
+			Url:       found.URL,
+			ChirpID:   chirp.ID,
+		})
+		if err != nil {
+			return chirp, err
+		}
+		body.WriteString(chirp.Body[prev:found.Start])
+		body.WriteString(cfg.shortLinkPrefix() + link.Code)
+		prev = found.End
+		changed = true
+	}
+	if !changed {
+		return chirp, nil
+	}
+	body.WriteString(chirp.Body[prev:])
+	return q.SetChirpBody(ctx, database.SetChirpBodyParams{
+		ID:   chirp.ID,
+		Body: body.String(),
+	})
+}
+
+// referrerHost reduces a Referer header to its host so clicks group by
+// site rather than by page. Clicks without one are grouped under "".
+func referrerHost(referer string) string {
+	u, err := url.Parse(referer)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
+
+func (cfg *apiConfig) followLink(w http.ResponseWriter, r *http.Request) {
+	// a link is only as visible as the chirp it was posted in
+	link, err := cfg.db.GetLinkByCode(r.Context(), database.GetLinkByCodeParams{
+		Code:     r.PathValue("code"),
+		ViewerID: cfg.viewerID(r),
+	})
+	if err != nil {
+		http.Error(w, http.StatusText(404), 404)
+		return
+	}
+	err = cfg.db.RecordLinkClick(r.Context(), database.RecordLinkClickParams{
+		LinkID:   link.ID,
+		Bucket:   time.Now().Truncate(linkClickBucket),
+		Referrer: referrerHost(r.Referer()),
+	})
+	if err != nil {
+		// losing a click is better than breaking the link
+		log.Printf("failed to record click on %s: %v\n", link.Code, err)
+	}
+	http.Redirect(w, r, link.Url, http.StatusFound)
+}
+
+func (cfg *apiConfig) getChirpLinks(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Println("no bearer")
+		w.WriteHeader(401)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+	userID, err := cfg.validateToken(r.Context(), token)
+	if err != nil {
+		log.Printf("jwt error: %v", err)
+		w.WriteHeader(401)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+	id, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		msg := fmt.Sprintf("400 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 400)
+		return
+	}
+	chirp, err := cfg.db.GetChirp(r.Context(), database.GetChirpParams{
+		ID:       id,
+		ViewerID: uuid.NullUUID{UUID: userID, Valid: true},
+	})
+	if err != nil {
+		msg := fmt.Sprintf("404 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 404)
+		return
+	}
+	if chirp.UserID.UUID != userID {
+		http.Error(w, http.StatusText(403), 403)
+		return
+	}
+	links, err := cfg.db.ListChirpLinks(r.Context(), chirp.ID)
+	if err != nil {
+		msg := fmt.Sprintf("500 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	clicks, err := cfg.db.ListChirpLinkClicks(r.Context(), chirp.ID)
+	if err != nil {
+		msg := fmt.Sprintf("500 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+
+	respBody := make([]returnLink, 0, len(links))
+	indexByID := make(map[uuid.UUID]int, len(links))
+	for i, link := range links {
+		indexByID[link.ID] = i
+		respBody = append(respBody, returnLink{
+			Code:      link.Code,
+			URL:       link.Url,
+			ShortURL:  cfg.shortLinkPrefix() + link.Code,
+			Timeline:  []returnLinkBucket{},
+			Referrers: []returnLinkReferrer{},
+		})
+	}
+	// rows come ordered by bucket, so each timeline is built in order
+	for _, row := range clicks {
+		link := &respBody[indexByID[row.LinkID]]
+		link.Clicks += row.Clicks
+		if n := len(link.Timeline); n > 0 && link.Timeline[n-1].Bucket.Equal(row.Bucket) {
+			link.Timeline[n-1].Clicks += row.Clicks
+		} else {
+			link.Timeline = append(link.Timeline, returnLinkBucket{Bucket: row.Bucket, Clicks: row.Clicks})
+		}
+		found := false
+		for i := range link.Referrers {
+			if link.Referrers[i].Referrer == row.Referrer {
+				link.Referrers[i].Clicks += row.Clicks
+				found = true
+				break
+			}
+		}
+		if !found {
+			link.Referrers = append(link.Referrers, returnLinkReferrer{Referrer: row.Referrer, Clicks: row.Clicks})
+		}
+	}
+
+	dat, errMarshal := json.Marshal(respBody)
+	if errMarshal != nil {
+		msg := fmt.Sprintf("500 - %s", errMarshal)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+	w.Write(dat)
+}
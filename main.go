@@ -1,61 +1,328 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/google/uuid"
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
 	"github.com/uncomfyhalomacro/chirpy/internal/auth"
+	"github.com/uncomfyhalomacro/chirpy/internal/chirpquery"
 	"github.com/uncomfyhalomacro/chirpy/internal/database"
+	"github.com/uncomfyhalomacro/chirpy/internal/entities"
+	"github.com/uncomfyhalomacro/chirpy/internal/entitlements"
+	"github.com/uncomfyhalomacro/chirpy/internal/media"
+	"github.com/uncomfyhalomacro/chirpy/internal/pagination"
+	"github.com/uncomfyhalomacro/chirpy/internal/ratelimit"
+	"github.com/uncomfyhalomacro/chirpy/internal/validation"
 	"log"
 	"net/http"
 	"os"
-	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
+	"unicode/utf8"
 )
 
 type apiConfig struct {
 	fileserverHits atomic.Int32
+	conn           *sql.DB
 	db             *database.Queries
 	tokenSecret    string
 	polkaSecret    string
+	mediaDir       string
+	restoreWindow  time.Duration
+	limiter        *ratelimit.Limiter
+	validators     validation.Pipeline
+	publicURL      string
+	exportDir      string
+	exportWake     chan struct{}
+
+	profanityFile    string
+	profanityLocales []string
+	profanity        atomic.Pointer[profanityLists]
+	adminKey         string
+	// profanityMu keeps a slower reload from storing older lists over a
+	// newer one.
+	profanityMu sync.Mutex
 }
 
 type postDataShape struct {
-	UserID uuid.UUID `json:"user_id"`
-	Body   string    `json:"body"`
+	UserID     *uuid.UUID `json:"user_id"`
+	Body       string     `json:"body"`
+	ReplyTo    *uuid.UUID `json:"reply_to"`
+	PublishAt  *time.Time `json:"publish_at"`
+	Poll       *pollShape `json:"poll"`
+	Visibility string     `json:"visibility"`
+
+	ContentWarning string `json:"content_warning"`
+	Sensitive      bool   `json:"sensitive"`
 }
 
 type returnErrChirp struct {
-	Err string `json:"error"`
+	Err        string                 `json:"error"`
+	Violations []validation.Violation `json:"violations,omitempty"`
 }
 
 type returnValidChirp struct {
-	ID        uuid.UUID `json:"id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	Body      string    `json:"body"`
-	UserID    uuid.UUID `json:"user_id"`
+	ID         uuid.UUID  `json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	Body       string     `json:"body"`
+	UserID     *uuid.UUID `json:"user_id"`
+	Edited     bool       `json:"edited"`
+	ReplyTo    *uuid.UUID `json:"reply_to"`
+	Tombstone  bool       `json:"tombstone,omitempty"`
+	PublishAt  *time.Time `json:"publish_at,omitempty"`
+	Visibility string     `json:"visibility"`
+
+	// PublishFailed is set on a scheduled chirp the server could not
+	// publish; rescheduling it tries again.
+	PublishFailed bool `json:"publish_failed,omitempty"`
+
+	ContentWarning *string `json:"content_warning,omitempty"`
+	Sensitive      bool    `json:"sensitive"`
+	// Collapsed is set when the body, mentions, attachments and poll
+	// were left out because of the content warning or the flag.
+	Collapsed bool `json:"collapsed,omitempty"`
+
+	RechirpOf    *returnValidChirp  `json:"rechirp_of,omitempty"`
+	QuoteOf      *returnValidChirp  `json:"quote_of,omitempty"`
+	RechirpCount int64              `json:"rechirp_count"`
+	LikeCount    int64              `json:"like_count"`
+	LikedByMe    bool               `json:"liked_by_me"`
+	Mentions     []returnMention    `json:"mentions"`
+	Attachments  []returnAttachment `json:"attachments"`
+	Poll         *returnPoll        `json:"poll,omitempty"`
+}
+
+type returnMention struct {
+	UserID uuid.UUID `json:"user_id"`
+	Handle string    `json:"handle"`
+	Start  int32     `json:"start"`
+	End    int32     `json:"end"`
+}
+
+type chirpsPage struct {
+	Chirps     []returnValidChirp `json:"chirps"`
+	NextCursor string             `json:"next_cursor,omitempty"`
 }
 
 type UserLoginDetail struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
 	Expiry   int64  `json:"expires_in_seconds"`
+	Handle   string `json:"handle"`
 }
 
 type returnUser struct {
-	ID           uuid.UUID `json:"id"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
-	Email        string    `json:"email"`
-	Token        string    `json:"token"`
-	RefreshToken string    `json:"refresh_token"`
-	IsChirpyRed  bool      `json:"is_chirpy_red"`
+	ID           uuid.UUID  `json:"id"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	Email        string     `json:"email"`
+	Token        string     `json:"token"`
+	RefreshToken string     `json:"refresh_token"`
+	IsChirpyRed  bool       `json:"is_chirpy_red"`
+	Handle       string     `json:"handle,omitempty"`
+	DeleteAfter  *time.Time `json:"delete_after,omitempty"`
+}
+
+func chirpToJSON(chirp database.Chirp) returnValidChirp {
+	respBody := returnValidChirp{
+		ID:          chirp.ID,
+		CreatedAt:   chirp.CreatedAt,
+		UpdatedAt:   chirp.UpdatedAt,
+		Body:        chirp.Body,
+		Edited:      chirp.EditedAt.Valid,
+		Tombstone:   chirp.IsTombstone,
+		Visibility:  chirp.Visibility,
+		Mentions:    []returnMention{},
+		Attachments: []returnAttachment{},
+	}
+	// tombstones outlive the account of their author
+	if chirp.UserID.Valid {
+		respBody.UserID = &chirp.UserID.UUID
+	}
+	if chirp.ParentChirpID.Valid {
+		respBody.ReplyTo = &chirp.ParentChirpID.UUID
+	}
+	if chirp.PublishAt.Valid {
+		respBody.PublishAt = &chirp.PublishAt.Time
+	}
+	respBody.PublishFailed = chirp.PublishFailedAt.Valid
+	if chirp.ContentWarning.Valid {
+		respBody.ContentWarning = &chirp.ContentWarning.String
+	}
+	respBody.Sensitive = chirp.Sensitive
+	return respBody
+}
+
+// loadChirpsJSON converts chirps for a response together with everything
+// attached to them. Attached data is loaded with one query per kind for the
+// whole slice, never one query per chirp.
+func (cfg *apiConfig) loadChirpsJSON(ctx context.Context, viewerID uuid.NullUUID, chirps []database.Chirp) ([]returnValidChirp, error) {
+	respBodies := make([]returnValidChirp, 0, len(chirps))
+	if len(chirps) == 0 {
+		return respBodies, nil
+	}
+	ids := make([]uuid.UUID, 0, len(chirps))
+	var embeddedIDs, pollIDs []uuid.UUID
+	for _, chirp := range chirps {
+		respBodies = append(respBodies, chirpToJSON(chirp))
+		ids = append(ids, chirp.ID)
+		if !chirp.IsTombstone {
+			pollIDs = append(pollIDs, chirp.ID)
+		}
+		if chirp.RechirpOfID.Valid {
+			embeddedIDs = append(embeddedIDs, chirp.RechirpOfID.UUID)
+		}
+		if chirp.QuoteOfID.Valid {
+			embeddedIDs = append(embeddedIDs, chirp.QuoteOfID.UUID)
+		}
+	}
+
+	rechirpCounts, err := cfg.db.GetRechirpCounts(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	countByID := make(map[uuid.UUID]int64, len(rechirpCounts))
+	for _, row := range rechirpCounts {
+		countByID[row.ChirpID] = row.RechirpCount
+	}
+	likeStats, err := cfg.db.GetLikeStats(ctx, database.GetLikeStatsParams{
+		ViewerID: viewerID,
+		ChirpIds: ids,
+	})
+	if err != nil {
+		return nil, err
+	}
+	likesByID := make(map[uuid.UUID]database.GetLikeStatsRow, len(likeStats))
+	for _, row := range likeStats {
+		likesByID[row.ChirpID] = row
+	}
+	mentions, err := cfg.db.GetChirpMentions(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	mentionsByID := map[uuid.UUID][]returnMention{}
+	for _, row := range mentions {
+		mentionsByID[row.ChirpID] = append(mentionsByID[row.ChirpID], returnMention{
+			UserID: row.UserID,
+			Handle: row.Handle,
+			Start:  row.StartOffset,
+			End:    row.EndOffset,
+		})
+	}
+	attachments, err := cfg.db.GetChirpAttachments(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	attachmentsByID := map[uuid.UUID][]returnAttachment{}
+	for _, attachment := range attachments {
+		attachmentsByID[attachment.ChirpID] = append(attachmentsByID[attachment.ChirpID], attachmentToJSON(attachment))
+	}
+	pollsByID, err := cfg.loadPolls(ctx, viewerID, pollIDs)
+	if err != nil {
+		return nil, err
+	}
+	expand, err := cfg.expandSensitive(ctx, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	embeddedByID := map[uuid.UUID]returnValidChirp{}
+	if len(embeddedIDs) > 0 {
+		embedded, err := cfg.db.GetChirpsByIDs(ctx, database.GetChirpsByIDsParams{
+			Ids:      embeddedIDs,
+			ViewerID: viewerID,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, chirp := range embedded {
+			respBody := chirpToJSON(chirp)
+			collapseChirp(&respBody, viewerID, expand)
+			embeddedByID[chirp.ID] = respBody
+		}
+	}
+
+	for i, chirp := range chirps {
+		respBodies[i].RechirpCount = countByID[chirp.ID]
+		respBodies[i].LikeCount = likesByID[chirp.ID].LikeCount
+		respBodies[i].LikedByMe = likesByID[chirp.ID].LikedByMe
+		if chirpMentions, ok := mentionsByID[chirp.ID]; ok {
+			respBodies[i].Mentions = chirpMentions
+		}
+		if chirpAttachments, ok := attachmentsByID[chirp.ID]; ok {
+			respBodies[i].Attachments = chirpAttachments
+		}
+		respBodies[i].Poll = pollsByID[chirp.ID]
+		if original, ok := embeddedByID[chirp.RechirpOfID.UUID]; ok && chirp.RechirpOfID.Valid {
+			respBodies[i].RechirpOf = &original
+		}
+		if original, ok := embeddedByID[chirp.QuoteOfID.UUID]; ok && chirp.QuoteOfID.Valid {
+			respBodies[i].QuoteOf = &original
+		}
+		collapseChirp(&respBodies[i], viewerID, expand)
+	}
+	return respBodies, nil
+}
+
+func (cfg *apiConfig) loadChirpJSON(ctx context.Context, viewerID uuid.NullUUID, chirp database.Chirp) (returnValidChirp, error) {
+	respBodies, err := cfg.loadChirpsJSON(ctx, viewerID, []database.Chirp{chirp})
+	if err != nil {
+		return returnValidChirp{}, err
+	}
+	return respBodies[0], nil
+}
+
+// callerEntitlements looks up what the authenticated caller's plan allows
+// and writes an error response itself if that fails.
+func (cfg *apiConfig) callerEntitlements(w http.ResponseWriter, r *http.Request, userID uuid.UUID) (entitlements.Entitlements, bool) {
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		msg := fmt.Sprintf("500 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return entitlements.Entitlements{}, false
+	}
+	return entitlements.For(user.IsChirpyRed), true
+}
+
+// validateToken checks an access token like auth.ValidateJWT and also
+// refuses it while the account is scheduled for deletion. Access tokens
+// cannot be revoked, so this is what signs such an account out.
+func (cfg *apiConfig) validateToken(ctx context.Context, token string) (uuid.UUID, error) {
+	userID, err := auth.ValidateJWT(token, cfg.tokenSecret)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	user, err := cfg.db.GetUserByID(ctx, userID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if user.DeleteAfter.Valid {
+		return uuid.Nil, errors.New("the account is scheduled for deletion")
+	}
+	return userID, nil
+}
+
+// viewerID identifies the caller of a public endpoint from an optional
+// bearer token. A missing or invalid token means an anonymous viewer.
+func (cfg *apiConfig) viewerID(r *http.Request) uuid.NullUUID {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		return uuid.NullUUID{}
+	}
+	userID, err := cfg.validateToken(r.Context(), token)
+	if err != nil {
+		return uuid.NullUUID{}
+	}
+	return uuid.NullUUID{UUID: userID, Valid: true}
 }
 
 func (cfg *apiConfig) middlewareMetricsInc(next http.Handler) http.Handler {
@@ -77,6 +344,10 @@ func (cfg *apiConfig) chirps(w http.ResponseWriter, r *http.Request) {
 		cfg.getChirps(w, r)
 		return
 	}
+	if r.Method == "PUT" {
+		cfg.putChirps(w, r)
+		return
+	}
 	if r.Method == "DELETE" {
 		cfg.deleteChirps(w, r)
 		return
@@ -91,7 +362,7 @@ func (cfg *apiConfig) deleteChirps(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte("Unauthorized"))
 		return
 	}
-	userID, err := auth.ValidateJWT(token, cfg.tokenSecret)
+	userID, err := cfg.validateToken(r.Context(), token)
 	if err != nil {
 		log.Printf("jwt error: %v", err)
 		w.WriteHeader(401)
@@ -107,18 +378,24 @@ func (cfg *apiConfig) deleteChirps(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, msg, 500)
 			return
 		}
-		chirp, err := cfg.db.GetChirp(r.Context(), id)
+		chirp, err := cfg.db.GetChirp(r.Context(), database.GetChirpParams{
+			ID:       id,
+			ViewerID: uuid.NullUUID{UUID: userID, Valid: true},
+		})
 		if err != nil {
 			msg := fmt.Sprintf("404 - %s", err)
 			log.Printf("%s\n", msg)
 			http.Error(w, msg, 404)
 			return
 		}
-		if chirp.UserID != userID {
+		if chirp.UserID.UUID != userID {
 			http.Error(w, http.StatusText(403), 403)
 			return
 		}
-		err = cfg.db.DeleteChirp(r.Context(), chirp.ID)
+		err = cfg.db.SoftDeleteChirp(r.Context(), database.SoftDeleteChirpParams{
+			ID:        chirp.ID,
+			DeletedAt: sql.NullTime{Time: time.Now(), Valid: true},
+		})
 		if err != nil {
 			http.Error(w, http.StatusText(500), 500)
 			return
@@ -129,50 +406,180 @@ func (cfg *apiConfig) deleteChirps(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (cfg *apiConfig) getChirp(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		msg := fmt.Sprintf("400 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 400)
+		return
+	}
+	viewerID := cfg.viewerID(r)
+	chirp, err := cfg.db.GetChirp(r.Context(), database.GetChirpParams{
+		ID:       id,
+		ViewerID: viewerID,
+	})
+	if err != nil {
+		msg := fmt.Sprintf("404 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 404)
+		return
+	}
+	respBody, err := cfg.loadChirpJSON(r.Context(), viewerID, chirp)
+	if err != nil {
+		msg := fmt.Sprintf("500 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	dat, errMarshal := json.Marshal(respBody)
+	if errMarshal != nil {
+		msg := fmt.Sprintf("500 - %s", errMarshal)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+	w.Write(dat)
+}
+
+// listChirpsParams turns a parsed query into arguments for ListChirps,
+// leaving every filter that was not asked for NULL.
+func listChirpsParams(q chirpquery.Query, viewerID uuid.NullUUID) database.ListChirpsParams {
+	params := database.ListChirpsParams{
+		AuthorIds:  q.AuthorIDs,
+		ViewerID:   viewerID,
+		SortKey:    string(q.Sort),
+		Descending: q.Descending,
+		// Fetch one extra row so we know whether there is a next page.
+		PageLimit: q.Limit + 1,
+	}
+	if q.Sort == chirpquery.SortRelevance {
+		params.SortKey = "rank"
+	}
+	if q.Text != "" {
+		params.Query = sql.NullString{String: q.Text, Valid: true}
+	}
+	if !q.Since.IsZero() {
+		params.Since = sql.NullTime{Time: q.Since, Valid: true}
+	}
+	if !q.Until.IsZero() {
+		params.Until = sql.NullTime{Time: q.Until, Valid: true}
+	}
+	if q.HasMedia != nil {
+		params.HasMedia = sql.NullBool{Bool: *q.HasMedia, Valid: true}
+	}
+	if q.MinLikes > 0 {
+		params.MinLikes = sql.NullInt32{Int32: q.MinLikes, Valid: true}
+	}
+	if q.Cursor != nil {
+		params.AfterRank = sql.NullFloat64{Float64: float64(q.Cursor.Rank), Valid: true}
+		params.AfterTime = sql.NullTime{Time: q.Cursor.Time, Valid: true}
+		params.AfterID = uuid.NullUUID{UUID: q.Cursor.ID, Valid: true}
+	}
+	return params
+}
+
 func (cfg *apiConfig) getChirps(w http.ResponseWriter, r *http.Request) {
-	sortKind := r.URL.Query().Get("sort")
-	author_id := r.URL.Query().Get("author_id")
-	pathValue := r.PathValue("chirpID")
-	if author_id != "" && pathValue != "" {
-		http.Error(w, http.StatusText(409), 409)
+	if r.PathValue("chirpID") != "" {
+		cfg.getChirp(w, r)
 		return
 	}
-	if author_id != "" && pathValue == "" {
-		id, err := uuid.Parse(author_id)
-		if err != nil {
-			msg := fmt.Sprintf("500 - %s", err)
-			log.Printf("%s\n", msg)
-			http.Error(w, msg, 500)
-			return
+	q, err := chirpquery.Parse(r.URL.Query())
+	if err != nil {
+		msg := fmt.Sprintf("400 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 400)
+		return
+	}
+	viewerID := cfg.viewerID(r)
+	rows, err := cfg.db.ListChirps(r.Context(), listChirpsParams(q, viewerID))
+	if err != nil {
+		msg := fmt.Sprintf("500 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	var page chirpsPage
+	if len(rows) > int(q.Limit) {
+		rows = rows[:q.Limit]
+		last := rows[len(rows)-1]
+		cursor := pagination.Cursor{Time: last.Chirp.CreatedAt, ID: last.Chirp.ID}
+		switch q.Sort {
+		case chirpquery.SortUpdatedAt:
+			cursor.Time = last.Chirp.UpdatedAt
+		case chirpquery.SortRelevance:
+			cursor.Rank = last.Rank
 		}
-		chirps, err := cfg.db.GetChirpsByUserID(r.Context(), id)
-		var returnValidChirps []returnValidChirp
+		page.NextCursor = cursor.Encode()
+	}
+	chirps := make([]database.Chirp, 0, len(rows))
+	for _, row := range rows {
+		chirps = append(chirps, row.Chirp)
+	}
+	page.Chirps, err = cfg.loadChirpsJSON(r.Context(), viewerID, chirps)
+	if err != nil {
+		msg := fmt.Sprintf("500 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+
+	dat, errMarshal := json.Marshal(page)
+	if errMarshal != nil {
+		msg := fmt.Sprintf("500 - %s", errMarshal)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+	w.Write(dat)
+}
+
+func (cfg *apiConfig) postChirps(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Println("no bearer")
+		w.WriteHeader(401)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+	userID, err := cfg.validateToken(r.Context(), token)
+	if err != nil {
+		log.Printf("jwt error: %v", err)
+		w.WriteHeader(401)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+	perks, ok := cfg.callerEntitlements(w, r, userID)
+	if !ok {
+		return
+	}
+	if !cfg.limiter.Allow(userID, perks.ChirpsPerMinute) {
+		http.Error(w, "429 - you are posting chirps too quickly", 429)
+		return
+	}
+	var postData postDataShape
+	var images []media.Image
+	if isMultipart(r) {
+		postData, images, err = parseChirpForm(w, r, perks.MaxAttachments)
 		if err != nil {
-			msg := fmt.Sprintf("404 - %s", err)
+			msg := fmt.Sprintf("%d - %s", attachmentErrorStatus(err), err)
 			log.Printf("%s\n", msg)
-			http.Error(w, msg, 404)
+			http.Error(w, msg, attachmentErrorStatus(err))
 			return
 		}
-		for _, chirp := range chirps {
-			respBody := returnValidChirp{
-				ID:        chirp.ID,
-				CreatedAt: chirp.CreatedAt,
-				UpdatedAt: chirp.UpdatedAt,
-				Body:      chirp.Body,
-				UserID:    chirp.UserID,
-			}
-			returnValidChirps = append(returnValidChirps, respBody)
-		}
-		if sortKind == "desc" {
-			sort.Slice(returnValidChirps, func(i, j int) bool { return returnValidChirps[i].CreatedAt.After(returnValidChirps[j].CreatedAt) })
-		} else if sortKind == "asc" {
-			log.Println("stick to old sort")
-		} else {
-			w.WriteHeader(403)
-			w.Write([]byte("sort value should be `desc` or `asc`"))
-			return
+	} else {
+		decoder := json.NewDecoder(r.Body)
+		err = decoder.Decode(&postData)
+	}
+	if err != nil {
+		respBody := returnErrChirp{
+			Err: fmt.Sprintf("%v", err),
 		}
-		dat, errMarshal := json.Marshal(returnValidChirps)
+		dat, errMarshal := json.Marshal(respBody)
 		if errMarshal != nil {
 			msg := fmt.Sprintf("500 - %s", errMarshal)
 			log.Printf("%s\n", msg)
@@ -180,74 +587,85 @@ func (cfg *apiConfig) getChirps(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(200)
+		w.WriteHeader(500)
 		w.Write(dat)
 		return
 	}
-	if pathValue != "" && author_id == "" {
-		id, err := uuid.Parse(pathValue)
-		if err != nil {
-			msg := fmt.Sprintf("500 - %s", err)
-			log.Printf("%s\n", msg)
-			http.Error(w, msg, 500)
-			return
-		}
-		chirp, err := cfg.db.GetChirp(r.Context(), id)
+	cleanedBody, flagged, violations := cfg.cleanChirpBody(postData.Body, perks.MaxChirpLength)
+	if len(violations) > 0 {
+		writeViolations(w, violations)
+		return
+	}
+	visibility, err := parseVisibility(postData.Visibility)
+	if err != nil {
+		msg := fmt.Sprintf("400 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 400)
+		return
+	}
+	contentWarning, err := parseContentWarning(postData.ContentWarning)
+	if err != nil {
+		msg := fmt.Sprintf("400 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 400)
+		return
+	}
+	params := database.CreateChirpParams{
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+		Body:           cleanedBody,
+		UserID:         userID,
+		Visibility:     visibility,
+		ContentWarning: contentWarning,
+		Sensitive:      postData.Sensitive || flagged,
+	}
+	if postData.ReplyTo != nil {
+		parent, err := cfg.db.GetChirp(r.Context(), database.GetChirpParams{
+			ID:       *postData.ReplyTo,
+			ViewerID: uuid.NullUUID{UUID: userID, Valid: true},
+		})
 		if err != nil {
 			msg := fmt.Sprintf("404 - %s", err)
 			log.Printf("%s\n", msg)
 			http.Error(w, msg, 404)
 			return
 		}
-		respBody := returnValidChirp{
-			ID:        chirp.ID,
-			CreatedAt: chirp.CreatedAt,
-			UpdatedAt: chirp.UpdatedAt,
-			Body:      chirp.Body,
-			UserID:    chirp.UserID,
+		params.ParentChirpID = uuid.NullUUID{UUID: parent.ID, Valid: true}
+	}
+	if postData.PublishAt != nil {
+		if !postData.PublishAt.After(time.Now()) {
+			http.Error(w, "400 - publish_at should be in the future", 400)
+			return
 		}
-		dat, errMarshal := json.Marshal(respBody)
-		if errMarshal != nil {
-			msg := fmt.Sprintf("500 - %s", errMarshal)
+		params.PublishAt = sql.NullTime{Time: postData.PublishAt.In(time.Local), Valid: true}
+	}
+	if postData.Poll != nil {
+		opensAt := params.CreatedAt
+		if params.PublishAt.Valid {
+			opensAt = params.PublishAt.Time
+		}
+		if err = checkPoll(postData.Poll, opensAt); err != nil {
+			msg := fmt.Sprintf("400 - %s", err)
 			log.Printf("%s\n", msg)
-			http.Error(w, msg, 500)
+			http.Error(w, msg, 400)
 			return
 		}
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(200)
-		w.Write(dat)
-		return
-
 	}
-	chirps, err := cfg.db.GetChirps(r.Context())
+	chirp, err := cfg.createChirp(r.Context(), params, images, postData.Poll)
 	if err != nil {
 		msg := fmt.Sprintf("500 - %s", err)
-		log.Printf("%s\n", msg)
+		log.Printf("failed to create chirp! %s\n", msg)
 		http.Error(w, msg, 500)
 		return
 	}
-	var chirpJSON []returnValidChirp
-	for _, chirp := range chirps {
-		chirpJSON = append(chirpJSON, returnValidChirp{
-			ID:        chirp.ID,
-			CreatedAt: chirp.CreatedAt,
-			UpdatedAt: chirp.UpdatedAt,
-			Body:      chirp.Body,
-			UserID:    chirp.UserID,
-		})
-	}
-
-	if sortKind == "desc" {
-		sort.Slice(chirpJSON, func(i, j int) bool { return chirpJSON[i].CreatedAt.After(chirpJSON[j].CreatedAt) })
-	} else if sortKind == "asc" {
-		log.Println("stick to old sort")
-	} else {
-		w.WriteHeader(403)
-		w.Write([]byte("sort value should be `desc` or `asc`"))
+	respBody, err := cfg.loadChirpJSON(r.Context(), uuid.NullUUID{UUID: userID, Valid: true}, chirp)
+	if err != nil {
+		msg := fmt.Sprintf("500 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
 		return
 	}
-
-	dat, errMarshal := json.Marshal(chirpJSON)
+	dat, errMarshal := json.Marshal(respBody)
 	if errMarshal != nil {
 		msg := fmt.Sprintf("500 - %s", errMarshal)
 		log.Printf("%s\n", msg)
@@ -255,11 +673,11 @@ func (cfg *apiConfig) getChirps(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(200)
+	w.WriteHeader(201)
 	w.Write(dat)
 }
 
-func (cfg *apiConfig) postChirps(w http.ResponseWriter, r *http.Request) {
+func (cfg *apiConfig) putChirps(w http.ResponseWriter, r *http.Request) {
 	token, err := auth.GetBearerToken(r.Header)
 	if err != nil {
 		log.Println("no bearer")
@@ -267,70 +685,220 @@ func (cfg *apiConfig) postChirps(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte("Unauthorized"))
 		return
 	}
-	userID, err := auth.ValidateJWT(token, cfg.tokenSecret)
+	userID, err := cfg.validateToken(r.Context(), token)
 	if err != nil {
 		log.Printf("jwt error: %v", err)
 		w.WriteHeader(401)
 		w.Write([]byte("Unauthorized"))
 		return
 	}
+	id, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		msg := fmt.Sprintf("400 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 400)
+		return
+	}
+	perks, ok := cfg.callerEntitlements(w, r, userID)
+	if !ok {
+		return
+	}
+	if !perks.CanEditChirps {
+		http.Error(w, "403 - editing chirps is a Chirpy Red perk", 403)
+		return
+	}
 	var postData postDataShape
 	decoder := json.NewDecoder(r.Body)
 	err = decoder.Decode(&postData)
 	if err != nil {
-		respBody := returnErrChirp{
-			Err: fmt.Sprintf("%v", err),
+		msg := fmt.Sprintf("400 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 400)
+		return
+	}
+	cleanedBody, flagged, violations := cfg.cleanChirpBody(postData.Body, perks.MaxChirpLength)
+	if len(violations) > 0 {
+		writeViolations(w, violations)
+		return
+	}
+
+	tx, err := cfg.conn.BeginTx(r.Context(), nil)
+	if err != nil {
+		msg := fmt.Sprintf("500 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+	chirp, err := qtx.GetChirpForUpdate(r.Context(), id)
+	if err != nil {
+		msg := fmt.Sprintf("404 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 404)
+		return
+	}
+	if chirp.UserID.UUID != userID {
+		http.Error(w, http.StatusText(403), 403)
+		return
+	}
+	if chirp.RechirpOfID.Valid {
+		http.Error(w, "400 - rechirps cannot be edited", 400)
+		return
+	}
+	if chirp.Body != cleanedBody {
+		now := time.Now()
+		_, err = qtx.AddChirpRevision(r.Context(), database.AddChirpRevisionParams{
+			ChirpID:    chirp.ID,
+			Body:       chirp.Body,
+			CreatedAt:  chirp.UpdatedAt,
+			ReplacedAt: now,
+		})
+		if err != nil {
+			msg := fmt.Sprintf("500 - %s", err)
+			log.Printf("failed to save chirp revision! %s\n", msg)
+			http.Error(w, msg, 500)
+			return
 		}
-		dat, errMarshal := json.Marshal(respBody)
-		if errMarshal != nil {
-			msg := fmt.Sprintf("500 - %s", errMarshal)
-			log.Printf("%s\n", msg)
+		chirp, err = qtx.UpdateChirpBody(r.Context(), database.UpdateChirpBodyParams{
+			ID:        chirp.ID,
+			Body:      cleanedBody,
+			UpdatedAt: now,
+		})
+		if err != nil {
+			msg := fmt.Sprintf("500 - %s", err)
+			log.Printf("failed to update chirp! %s\n", msg)
 			http.Error(w, msg, 500)
 			return
 		}
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(500)
-		w.Write(dat)
-		return
-	}
-	log.Printf("Before cleaned: %v\n", postData.Body)
-	cleanedBody := cleanProfaneBody(postData.Body)
-	log.Printf("After cleaned: %v\n", cleanedBody)
-	if len(postData.Body) > 140 {
-		respBody := returnErrChirp{
-			Err: "Chirp is too long",
+		chirp, err = cfg.saveChirpEntities(r.Context(), qtx, chirp)
+		if err != nil {
+			msg := fmt.Sprintf("500 - %s", err)
+			log.Printf("failed to save chirp entities! %s\n", msg)
+			http.Error(w, msg, 500)
+			return
 		}
-		dat, errMarshal := json.Marshal(respBody)
-		if errMarshal != nil {
-			msg := fmt.Sprintf("500 - %s", errMarshal)
+	}
+	// an edit can only add the flag, taking it off is up to moderators
+	if flagged && !chirp.Sensitive {
+		chirp, err = qtx.SetChirpSensitive(r.Context(), database.SetChirpSensitiveParams{
+			ID:        chirp.ID,
+			Sensitive: true,
+		})
+		if err != nil {
+			msg := fmt.Sprintf("500 - %s", err)
 			log.Printf("%s\n", msg)
 			http.Error(w, msg, 500)
 			return
 		}
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(400)
-		w.Write(dat)
+	}
+	if err = tx.Commit(); err != nil {
+		msg := fmt.Sprintf("500 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
 		return
 	}
-	params := database.CreateChirpParams{
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-		Body:      cleanedBody,
-		UserID:    userID,
+	respBody, err := cfg.loadChirpJSON(r.Context(), uuid.NullUUID{UUID: userID, Valid: true}, chirp)
+	if err != nil {
+		msg := fmt.Sprintf("500 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	dat, errMarshal := json.Marshal(respBody)
+	if errMarshal != nil {
+		msg := fmt.Sprintf("500 - %s", errMarshal)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+	w.Write(dat)
+}
+
+func (cfg *apiConfig) getChirpRevisions(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		msg := fmt.Sprintf("400 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 400)
+		return
+	}
+	chirp, err := cfg.db.GetChirp(r.Context(), database.GetChirpParams{
+		ID:       id,
+		ViewerID: cfg.viewerID(r),
+	})
+	if err != nil {
+		msg := fmt.Sprintf("404 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 404)
+		return
 	}
-	chirp, err := cfg.db.CreateChirp(r.Context(), params)
+	revisions, err := cfg.db.GetChirpRevisions(r.Context(), chirp.ID)
 	if err != nil {
 		msg := fmt.Sprintf("500 - %s", err)
-		log.Printf("failed to create chirp! %s\n", msg)
+		log.Printf("%s\n", msg)
 		http.Error(w, msg, 500)
 		return
 	}
-	respBody := returnValidChirp{
-		ID:        chirp.ID,
-		CreatedAt: chirp.CreatedAt,
-		UpdatedAt: chirp.UpdatedAt,
-		Body:      chirp.Body,
-		UserID:    chirp.UserID,
+	type returnRevision struct {
+		ID         uuid.UUID `json:"id"`
+		CreatedAt  time.Time `json:"created_at"`
+		ReplacedAt time.Time `json:"replaced_at"`
+		Body       string    `json:"body"`
+	}
+	revisionsJSON := []returnRevision{}
+	for _, revision := range revisions {
+		revisionsJSON = append(revisionsJSON, returnRevision{
+			ID:         revision.ID,
+			CreatedAt:  revision.CreatedAt,
+			ReplacedAt: revision.ReplacedAt,
+			Body:       revision.Body,
+		})
+	}
+	dat, errMarshal := json.Marshal(revisionsJSON)
+	if errMarshal != nil {
+		msg := fmt.Sprintf("500 - %s", errMarshal)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+	w.Write(dat)
+}
+
+// cleanChirpBody runs a chirp body through the configured validators and
+// the profanity lists and returns the body that should be saved and
+// whether a list flagged it as sensitive, or every rule it breaks.
+// maxLength comes from the author's entitlements. Profanity is masked
+// last so the validators judge what the author actually wrote.
+func (cfg *apiConfig) cleanChirpBody(body string, maxLength int) (string, bool, []validation.Violation) {
+	in := validation.Input{
+		Body:       body,
+		MaxLength:  maxLength,
+		LinkLength: utf8.RuneCountInString(cfg.shortLinkPrefix()) + linkCodeLength,
+	}
+	violations := cfg.validators.Run(&in)
+	result := cfg.profanity.Load().filter.Check(in.Body)
+	if len(result.Rejected) > 0 {
+		violations = append(violations, validation.Violation{
+			Code:    validation.CodeProfanity,
+			Field:   "body",
+			Message: fmt.Sprintf("Chirp contains blocked words: %s", strings.Join(result.Rejected, ", ")),
+		})
+	}
+	if len(violations) > 0 {
+		return "", false, violations
+	}
+	return result.Body, result.Flagged, nil
+}
+
+func writeViolations(w http.ResponseWriter, violations []validation.Violation) {
+	respBody := returnErrChirp{
+		Err:        violations[0].Message,
+		Violations: violations,
 	}
 	dat, errMarshal := json.Marshal(respBody)
 	if errMarshal != nil {
@@ -340,23 +908,189 @@ func (cfg *apiConfig) postChirps(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(201)
+	w.WriteHeader(400)
 	w.Write(dat)
 }
 
-func cleanProfaneBody(s string) string {
-	fields := strings.Split(s, " ")
-	badwords := map[string]bool{
-		"kerfuffle": true,
-		"sharbert":  true,
-		"fornax":    true,
+// createChirp stores a new chirp together with the entities extracted from
+// its body and its image attachments, in the order they were uploaded.
+// params.PublishAt makes it a scheduled chirp.
+func (cfg *apiConfig) createChirp(ctx context.Context, params database.CreateChirpParams, images []media.Image, poll *pollShape) (database.Chirp, error) {
+	tx, err := cfg.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+	chirp, err := cfg.insertChirp(ctx, qtx, params)
+	if err != nil {
+		return chirp, err
 	}
-	for idx, element := range fields {
-		if badwords[strings.ToLower(element)] {
-			fields[idx] = "****"
+	if poll != nil {
+		if err = savePoll(ctx, qtx, chirp.ID, poll); err != nil {
+			return chirp, err
 		}
 	}
-	return strings.Join(fields, " ")
+	var written []database.ChirpAttachment
+	for i, img := range images {
+		attachment, err := qtx.AddChirpAttachment(ctx, database.AddChirpAttachmentParams{
+			CreatedAt:   params.CreatedAt,
+			ChirpID:     chirp.ID,
+			Position:    int32(i),
+			ContentType: img.ContentType,
+			Width:       int32(img.Width),
+			Height:      int32(img.Height),
+		})
+		if err == nil {
+			err = cfg.writeAttachmentFiles(attachment, img)
+		}
+		if err != nil {
+			cfg.removeAttachmentFiles(written)
+			return chirp, err
+		}
+		written = append(written, attachment)
+	}
+	if err = tx.Commit(); err != nil {
+		cfg.removeAttachmentFiles(written)
+		return chirp, err
+	}
+	return chirp, nil
+}
+
+// insertChirp creates a chirp and, unless it is scheduled, the entities in
+// its body. Callers run it inside their own transaction.
+func (cfg *apiConfig) insertChirp(ctx context.Context, q *database.Queries, params database.CreateChirpParams) (database.Chirp, error) {
+	chirp, err := q.CreateChirp(ctx, params)
+	if err != nil {
+		return chirp, err
+	}
+	// scheduled chirps get their entities once they are published, so
+	// nobody is notified about a chirp they cannot see yet
+	if !chirp.PublishAt.Valid {
+		chirp, err = cfg.saveChirpEntities(ctx, q, chirp)
+	}
+	return chirp, err
+}
+
+// saveChirpEntities shortens the links in a chirp's body, stores its
+// hashtags and mentions and notifies the users it mentions. It returns the
+// chirp with its rewritten body.
+func (cfg *apiConfig) saveChirpEntities(ctx context.Context, q *database.Queries, chirp database.Chirp) (database.Chirp, error) {
+	// links go first: mention offsets have to point into the final body
+	chirp, err := cfg.saveChirpLinks(ctx, q, chirp)
+	if err != nil {
+		return chirp, err
+	}
+	if err = saveChirpHashtags(ctx, q, chirp); err != nil {
+		return chirp, err
+	}
+	mentioned, err := saveChirpMentions(ctx, q, chirp)
+	if err != nil {
+		return chirp, err
+	}
+	return chirp, notifyMentions(ctx, q, chirp, mentioned)
+}
+
+// saveChirpHashtags replaces the hashtags stored for a chirp with the ones
+// in its current body.
+func saveChirpHashtags(ctx context.Context, q *database.Queries, chirp database.Chirp) error {
+	if err := q.DeleteChirpHashtags(ctx, chirp.ID); err != nil {
+		return err
+	}
+	for _, tag := range entities.Hashtags(chirp.Body) {
+		hashtag, err := q.UpsertHashtag(ctx, database.UpsertHashtagParams{
+			Tag:       tag,
+			CreatedAt: chirp.UpdatedAt,
+		})
+		if err != nil {
+			return err
+		}
+		err = q.AddChirpHashtag(ctx, database.AddChirpHashtagParams{
+			ChirpID:   chirp.ID,
+			HashtagID: hashtag.ID,
+			CreatedAt: chirp.CreatedAt,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// saveChirpMentions resolves the @handles in a chirp's body against the
+// users table and replaces the mentions stored for it. Handles that do not
+// belong to anyone stay plain text. It returns the users that the chirp
+// did not mention before.
+func saveChirpMentions(ctx context.Context, q *database.Queries, chirp database.Chirp) ([]uuid.UUID, error) {
+	previous, err := q.GetChirpMentions(ctx, []uuid.UUID{chirp.ID})
+	if err != nil {
+		return nil, err
+	}
+	if err = q.DeleteChirpMentions(ctx, chirp.ID); err != nil {
+		return nil, err
+	}
+	mentions := entities.Mentions(chirp.Body)
+	if len(mentions) == 0 {
+		return nil, nil
+	}
+	handles := make([]string, 0, len(mentions))
+	for _, mention := range mentions {
+		handles = append(handles, mention.Handle)
+	}
+	users, err := q.GetUsersByHandles(ctx, handles)
+	if err != nil {
+		return nil, err
+	}
+	userByHandle := make(map[string]database.User, len(users))
+	for _, user := range users {
+		userByHandle[user.Handle.String] = user
+	}
+	seen := map[uuid.UUID]bool{}
+	for _, row := range previous {
+		seen[row.UserID] = true
+	}
+	var mentioned []uuid.UUID
+	for _, mention := range mentions {
+		user, ok := userByHandle[mention.Handle]
+		if !ok {
+			continue
+		}
+		err = q.AddChirpMention(ctx, database.AddChirpMentionParams{
+			ChirpID:     chirp.ID,
+			UserID:      user.ID,
+			StartOffset: int32(mention.Start),
+			EndOffset:   int32(mention.End),
+		})
+		if err != nil {
+			return nil, err
+		}
+		if !seen[user.ID] {
+			seen[user.ID] = true
+			mentioned = append(mentioned, user.ID)
+		}
+	}
+	return mentioned, nil
+}
+
+// notifyMentions notifies the mentioned users who can see chirp; the rest
+// are skipped by CreateNotification itself.
+func notifyMentions(ctx context.Context, q *database.Queries, chirp database.Chirp, userIDs []uuid.UUID) error {
+	for _, userID := range userIDs {
+		if userID == chirp.UserID.UUID {
+			continue
+		}
+		err := q.CreateNotification(ctx, database.CreateNotificationParams{
+			CreatedAt: time.Now(),
+			Kind:      notificationKindMention,
+			UserID:    userID,
+			ActorID:   chirp.UserID.UUID,
+			ChirpID:   chirp.ID,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func readiness(w http.ResponseWriter, _ *http.Request) {
@@ -419,6 +1153,15 @@ func (cfg *apiConfig) createUser(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(fmt.Sprintf("JSON decode error: %v", err)))
 		return
 	}
+	var handle sql.NullString
+	if postData.Handle != "" {
+		handle.String = entities.NormalizeHandle(postData.Handle)
+		handle.Valid = true
+		if !entities.ValidHandle(handle.String) {
+			http.Error(w, "400 - invalid handle", 400)
+			return
+		}
+	}
 	hashedPassword, err := auth.HashPassword(postData.Password)
 	if err != nil {
 		log.Printf("%v\n", err)
@@ -432,9 +1175,14 @@ func (cfg *apiConfig) createUser(w http.ResponseWriter, r *http.Request) {
 		UpdatedAt:      time.Now(),
 		Email:          postData.Email,
 		HashedPassword: hashedPassword,
+		Handle:         handle,
 	}
 
 	user, err := cfg.db.CreateUser(r.Context(), params)
+	if isUniqueViolation(err) {
+		http.Error(w, "409 - email or handle is already taken", 409)
+		return
+	}
 	if err != nil {
 		msg := fmt.Sprintf("500 - %s", err)
 		log.Printf("failed to create user! %s\n", msg)
@@ -448,6 +1196,7 @@ func (cfg *apiConfig) createUser(w http.ResponseWriter, r *http.Request) {
 		UpdatedAt:   user.UpdatedAt,
 		Email:       user.Email,
 		IsChirpyRed: user.IsChirpyRed,
+		Handle:      user.Handle.String,
 	}
 
 	dat, err := json.Marshal(responseJson)
@@ -488,6 +1237,10 @@ func (cfg *apiConfig) loginUser(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Unauthorized", 401)
 		return
 	}
+	if user.DeleteAfter.Valid {
+		http.Error(w, "403 - this account is scheduled for deletion, restore it with POST /api/users/restore", 403)
+		return
+	}
 
 	newJWTToken, err := auth.MakeJWT(user.ID, cfg.tokenSecret, expiresInSeconds)
 
@@ -513,6 +1266,7 @@ func (cfg *apiConfig) loginUser(w http.ResponseWriter, r *http.Request) {
 		Token:        newJWTToken,
 		RefreshToken: refreshToken,
 		IsChirpyRed:  user.IsChirpyRed,
+		Handle:       user.Handle.String,
 	}
 
 	dat, err := json.Marshal(responseJson)
@@ -550,7 +1304,7 @@ func (cfg *apiConfig) updateUser(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte("Unauthorized"))
 		return
 	}
-	userID, err := auth.ValidateJWT(token, cfg.tokenSecret)
+	userID, err := cfg.validateToken(r.Context(), token)
 	if err != nil {
 		log.Printf("jwt error: %v", err)
 		w.WriteHeader(401)
@@ -560,6 +1314,7 @@ func (cfg *apiConfig) updateUser(w http.ResponseWriter, r *http.Request) {
 	type updateVal struct {
 		Email    string `json:"email"`
 		Password string `json:"password"`
+		Handle   string `json:"handle"`
 	}
 	var postData updateVal
 	decoder := json.NewDecoder(r.Body)
@@ -569,17 +1324,44 @@ func (cfg *apiConfig) updateUser(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(fmt.Sprintf("JSON decode error: %v", err)))
 		return
 	}
+	handle := entities.NormalizeHandle(postData.Handle)
+	if postData.Handle != "" && !entities.ValidHandle(handle) {
+		http.Error(w, "400 - invalid handle", 400)
+		return
+	}
 	hashedPassword, err := auth.HashPassword(postData.Password)
 	if err != nil {
 		w.WriteHeader(500)
 		return
 	}
+	tx, err := cfg.conn.BeginTx(r.Context(), nil)
+	if err != nil {
+		msg := fmt.Sprintf("500 - %s", err)
+		log.Println(msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
 	params := database.UpdateUserDetailsParams{
 		Email:          postData.Email,
 		HashedPassword: hashedPassword,
 		ID:             userID,
 	}
-	updatedUser, err := cfg.db.UpdateUserDetails(r.Context(), params)
+	updatedUser, err := qtx.UpdateUserDetails(r.Context(), params)
+	if postData.Handle != "" && err == nil {
+		updatedUser, err = qtx.UpdateUserHandle(r.Context(), database.UpdateUserHandleParams{
+			ID:     userID,
+			Handle: sql.NullString{String: handle, Valid: true},
+		})
+	}
+	if isUniqueViolation(err) {
+		http.Error(w, "409 - email or handle is already taken", 409)
+		return
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
 
 	if err != nil {
 		msg := fmt.Sprintf("500 - %s", err)
@@ -593,6 +1375,7 @@ func (cfg *apiConfig) updateUser(w http.ResponseWriter, r *http.Request) {
 		CreatedAt: updatedUser.CreatedAt,
 		UpdatedAt: updatedUser.UpdatedAt,
 		Email:     updatedUser.Email,
+		Handle:    updatedUser.Handle.String,
 	}
 
 	dat, err := json.Marshal(responseJson)
@@ -747,16 +1530,87 @@ func main() {
 	dbURL := os.Getenv("DB_URL")
 	tokenSecret := os.Getenv("SIGNING_KEY")
 	polkaSecret := os.Getenv("POLKA_KEY")
+	mediaDir := os.Getenv("MEDIA_DIR")
+	if mediaDir == "" {
+		mediaDir = "media"
+	}
+	if err := os.MkdirAll(mediaDir, 0o755); err != nil {
+		log.Fatalf("failed to create media directory %s: %v\n", mediaDir, err)
+	}
+	exportDir := os.Getenv("EXPORT_DIR")
+	if exportDir == "" {
+		exportDir = "exports"
+	}
+	if err := os.MkdirAll(exportDir, 0o700); err != nil {
+		log.Fatalf("failed to create export directory %s: %v\n", exportDir, err)
+	}
+	restoreWindow := defaultRestoreWindow
+	if raw := os.Getenv("CHIRP_RESTORE_WINDOW"); raw != "" {
+		window, err := time.ParseDuration(raw)
+		if err != nil || window <= 0 {
+			log.Fatalf("CHIRP_RESTORE_WINDOW should be a positive duration, got %q\n", raw)
+		}
+		restoreWindow = window
+	}
+	publicURL := strings.TrimSuffix(os.Getenv("PUBLIC_URL"), "/")
+	if publicURL == "" {
+		publicURL = "http://localhost:8080"
+	}
+	validatorNames := validation.DefaultNames
+	if raw := os.Getenv("CHIRP_VALIDATORS"); raw != "" {
+		validatorNames = strings.Split(raw, ",")
+	}
+	maxLinks := validation.DefaultMaxLinks
+	if raw := os.Getenv("CHIRP_MAX_LINKS"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			log.Fatalf("CHIRP_MAX_LINKS should be a non-negative integer, got %q\n", raw)
+		}
+		maxLinks = n
+	}
+	validators, err := validation.New(validatorNames, maxLinks)
+	if err != nil {
+		log.Fatalf("invalid CHIRP_VALIDATORS: %v\n", err)
+	}
+	var profanityLocales []string
+	if raw := os.Getenv("PROFANITY_LOCALES"); raw != "" {
+		for _, locale := range strings.Split(raw, ",") {
+			profanityLocales = append(profanityLocales, strings.TrimSpace(locale))
+		}
+	}
 	db, err := sql.Open("postgres", dbURL)
 	if err != nil {
 		log.Fatalf("failed to connect to %s: %v\n", dbURL, err)
 	}
 	dbQueries := database.New(db)
 	apiCfg := apiConfig{
-		db:          dbQueries,
-		tokenSecret: tokenSecret,
-		polkaSecret: polkaSecret,
+		conn:          db,
+		db:            dbQueries,
+		tokenSecret:   tokenSecret,
+		polkaSecret:   polkaSecret,
+		mediaDir:      mediaDir,
+		restoreWindow: restoreWindow,
+		limiter:       ratelimit.New(),
+		validators:    validators,
+		publicURL:     publicURL,
+		exportDir:     exportDir,
+		exportWake:    make(chan struct{}, 1),
+
+		profanityFile:    os.Getenv("PROFANITY_FILE"),
+		profanityLocales: profanityLocales,
+		adminKey:         os.Getenv("ADMIN_KEY"),
+	}
+	if err := apiCfg.reloadProfanity(context.Background()); err != nil {
+		log.Fatalf("failed to load profanity lists: %v\n", err)
+	}
+	if len(os.Args) > 1 && os.Args[1] == "import" {
+		os.Exit(apiCfg.runImportCommand(os.Args[2:]))
 	}
+	go apiCfg.purgeDeletedChirps(context.Background())
+	go apiCfg.runScheduler(context.Background())
+	go apiCfg.runExports(context.Background())
+	go apiCfg.runAccountDeletions(context.Background())
+	go apiCfg.reloadProfanityOnSignal(context.Background())
 	curdir, err := os.Getwd()
 	if err != nil {
 		log.Fatalf("failed to get current directory: %v\n", err)
@@ -766,16 +1620,66 @@ func main() {
 	mux.Handle("POST /api/chirps", apiCfg.middlewareMetricsInc(http.HandlerFunc(apiCfg.chirps)))
 	mux.Handle("GET /api/chirps", apiCfg.middlewareMetricsInc(http.HandlerFunc(apiCfg.chirps)))
 	mux.Handle("GET /api/chirps/{chirpID}", apiCfg.middlewareMetricsInc(http.HandlerFunc(apiCfg.chirps)))
+	mux.Handle("PUT /api/chirps/{chirpID}", apiCfg.middlewareMetricsInc(http.HandlerFunc(apiCfg.chirps)))
 	mux.Handle("DELETE /api/chirps/{chirpID}", apiCfg.middlewareMetricsInc(http.HandlerFunc(apiCfg.chirps)))
+	mux.Handle("POST /api/chirps/import", apiCfg.middlewareMetricsInc(http.HandlerFunc(apiCfg.postImport)))
+	mux.Handle("GET /api/chirps/scheduled", apiCfg.middlewareMetricsInc(http.HandlerFunc(apiCfg.getScheduledChirps)))
+	mux.Handle("PUT /api/chirps/{chirpID}/schedule", apiCfg.middlewareMetricsInc(http.HandlerFunc(apiCfg.rescheduleChirp)))
+	mux.Handle("DELETE /api/chirps/{chirpID}/schedule", apiCfg.middlewareMetricsInc(http.HandlerFunc(apiCfg.cancelScheduledChirp)))
+	mux.Handle("POST /api/chirps/{chirpID}/restore", apiCfg.middlewareMetricsInc(http.HandlerFunc(apiCfg.restoreChirp)))
+	mux.Handle("POST /api/chirps/{chirpID}/poll/vote", apiCfg.middlewareMetricsInc(http.HandlerFunc(apiCfg.votePoll)))
+	mux.Handle("POST /api/chirps/{chirpID}/rechirp", apiCfg.middlewareMetricsInc(http.HandlerFunc(apiCfg.rechirp)))
+	mux.Handle("DELETE /api/chirps/{chirpID}/rechirp", apiCfg.middlewareMetricsInc(http.HandlerFunc(apiCfg.undoRechirp)))
+	mux.Handle("POST /api/chirps/{chirpID}/quote", apiCfg.middlewareMetricsInc(http.HandlerFunc(apiCfg.quoteChirp)))
+	mux.Handle("POST /api/chirps/{chirpID}/like", apiCfg.middlewareMetricsInc(http.HandlerFunc(apiCfg.likeChirp)))
+	mux.Handle("DELETE /api/chirps/{chirpID}/like", apiCfg.middlewareMetricsInc(http.HandlerFunc(apiCfg.unlikeChirp)))
+	mux.Handle("GET /api/chirps/{chirpID}/thread", apiCfg.middlewareMetricsInc(http.HandlerFunc(apiCfg.getChirpThread)))
+	mux.Handle("POST /api/chirps/{chirpID}/sensitive", apiCfg.middlewareMetricsInc(http.HandlerFunc(apiCfg.flagChirpSensitive)))
+	mux.Handle("DELETE /api/chirps/{chirpID}/sensitive", apiCfg.middlewareMetricsInc(http.HandlerFunc(apiCfg.unflagChirpSensitive)))
+	mux.Handle("GET /api/chirps/{chirpID}/links", apiCfg.middlewareMetricsInc(http.HandlerFunc(apiCfg.getChirpLinks)))
+	mux.Handle("GET /l/{code}", apiCfg.middlewareMetricsInc(http.HandlerFunc(apiCfg.followLink)))
+	mux.Handle("GET /api/chirps/{chirpID}/revisions", apiCfg.middlewareMetricsInc(http.HandlerFunc(apiCfg.getChirpRevisions)))
+	mux.Handle("GET /media/{name}", apiCfg.middlewareMetricsInc(http.HandlerFunc(apiCfg.serveMedia)))
 	mux.Handle("GET /api/healthz", apiCfg.middlewareMetricsInc(http.HandlerFunc(readiness)))
 	mux.Handle("POST /api/users", apiCfg.middlewareMetricsInc(http.HandlerFunc(apiCfg.createUser)))
 	mux.Handle("PUT /api/users", apiCfg.middlewareMetricsInc(http.HandlerFunc(apiCfg.updateUser)))
+	mux.Handle("DELETE /api/users/me", apiCfg.middlewareMetricsInc(http.HandlerFunc(apiCfg.deleteAccount)))
+	mux.Handle("POST /api/users/restore", apiCfg.middlewareMetricsInc(http.HandlerFunc(apiCfg.restoreAccount)))
+	mux.Handle("POST /api/chirps/{chirpID}/bookmark", apiCfg.middlewareMetricsInc(http.HandlerFunc(apiCfg.bookmarkChirp)))
+	mux.Handle("DELETE /api/chirps/{chirpID}/bookmark", apiCfg.middlewareMetricsInc(http.HandlerFunc(apiCfg.unbookmarkChirp)))
+	mux.Handle("GET /api/bookmarks", apiCfg.middlewareMetricsInc(http.HandlerFunc(apiCfg.getBookmarks)))
+	mux.Handle("GET /api/users/me/preferences", apiCfg.middlewareMetricsInc(http.HandlerFunc(apiCfg.getPreferences)))
+	mux.Handle("PUT /api/users/me/preferences", apiCfg.middlewareMetricsInc(http.HandlerFunc(apiCfg.putPreferences)))
+	mux.Handle("POST /api/users/me/export", apiCfg.middlewareMetricsInc(http.HandlerFunc(apiCfg.postExport)))
+	mux.Handle("GET /api/users/me/export", apiCfg.middlewareMetricsInc(http.HandlerFunc(apiCfg.getExport)))
+	mux.Handle("GET /api/users/{userID}/likes", apiCfg.middlewareMetricsInc(http.HandlerFunc(apiCfg.getUserLikes)))
+	mux.Handle("POST /api/users/{userID}/follow", apiCfg.middlewareMetricsInc(http.HandlerFunc(apiCfg.followUser)))
+	mux.Handle("DELETE /api/users/{userID}/follow", apiCfg.middlewareMetricsInc(http.HandlerFunc(apiCfg.unfollowUser)))
+	mux.Handle("GET /api/users/{userID}/followers", apiCfg.middlewareMetricsInc(http.HandlerFunc(apiCfg.getFollowers)))
+	mux.Handle("GET /api/users/{userID}/following", apiCfg.middlewareMetricsInc(http.HandlerFunc(apiCfg.getFollowing)))
+	mux.Handle("GET /api/feed", apiCfg.middlewareMetricsInc(http.HandlerFunc(apiCfg.getFeed)))
+	mux.Handle("GET /api/hashtags/trending", apiCfg.middlewareMetricsInc(http.HandlerFunc(apiCfg.getTrendingHashtags)))
+	mux.Handle("GET /api/hashtags/{tag}/chirps", apiCfg.middlewareMetricsInc(http.HandlerFunc(apiCfg.getHashtagChirps)))
+	mux.Handle("GET /api/drafts", apiCfg.middlewareMetricsInc(http.HandlerFunc(apiCfg.getDrafts)))
+	mux.Handle("POST /api/drafts", apiCfg.middlewareMetricsInc(http.HandlerFunc(apiCfg.postDraft)))
+	mux.Handle("GET /api/drafts/{draftID}", apiCfg.middlewareMetricsInc(http.HandlerFunc(apiCfg.getDraft)))
+	mux.Handle("PUT /api/drafts/{draftID}", apiCfg.middlewareMetricsInc(http.HandlerFunc(apiCfg.putDraft)))
+	mux.Handle("DELETE /api/drafts/{draftID}", apiCfg.middlewareMetricsInc(http.HandlerFunc(apiCfg.deleteDraft)))
+	mux.Handle("POST /api/drafts/{draftID}/publish", apiCfg.middlewareMetricsInc(http.HandlerFunc(apiCfg.publishDraft)))
+	mux.Handle("GET /api/notifications", apiCfg.middlewareMetricsInc(http.HandlerFunc(apiCfg.getNotifications)))
+	mux.Handle("POST /api/notifications/read", apiCfg.middlewareMetricsInc(http.HandlerFunc(apiCfg.readNotifications)))
 	mux.Handle("POST /api/login", apiCfg.middlewareMetricsInc(http.HandlerFunc(apiCfg.loginUser)))
 	mux.Handle("POST /api/revoke", apiCfg.middlewareMetricsInc(http.HandlerFunc(apiCfg.revokeToken)))
 	mux.Handle("POST /api/refresh", apiCfg.middlewareMetricsInc(http.HandlerFunc(apiCfg.refreshTheToken)))
 	mux.Handle("POST /api/polka/webhooks", apiCfg.middlewareMetricsInc(http.HandlerFunc(apiCfg.webhooks)))
 	mux.Handle("GET /admin/metrics", http.HandlerFunc(apiCfg.numberOfHits))
 	mux.Handle("POST /admin/reset", http.HandlerFunc(apiCfg.reset))
+	mux.Handle("GET /admin/profanity", http.HandlerFunc(apiCfg.getProfanityLists))
+	mux.Handle("POST /admin/profanity/lists", http.HandlerFunc(apiCfg.postProfanityList))
+	mux.Handle("DELETE /admin/profanity/lists/{name}", http.HandlerFunc(apiCfg.deleteProfanityList))
+	mux.Handle("POST /admin/profanity/lists/{name}/terms", http.HandlerFunc(apiCfg.postProfanityTerm))
+	mux.Handle("DELETE /admin/profanity/lists/{name}/terms/{term}", http.HandlerFunc(apiCfg.deleteProfanityTerm))
+	mux.Handle("POST /admin/profanity/reload", http.HandlerFunc(apiCfg.postProfanityReload))
 	server := http.Server{}
 	server.Addr = ":8080"
 	server.Handler = mux
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/uncomfyhalomacro/chirpy/internal/auth"
+	"github.com/uncomfyhalomacro/chirpy/internal/database"
+	"github.com/uncomfyhalomacro/chirpy/internal/pagination"
+	"log"
+	"net/http"
+	"time"
+)
+
+const notificationKindMention = "mention"
+
+type returnNotification struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	Kind      string    `json:"kind"`
+	ActorID   uuid.UUID `json:"actor_id"`
+	ChirpID   uuid.UUID `json:"chirp_id"`
+	Read      bool      `json:"read"`
+}
+
+type notificationsPage struct {
+	Notifications []returnNotification `json:"notifications"`
+	NextCursor    string               `json:"next_cursor,omitempty"`
+}
+
+func (cfg *apiConfig) getNotifications(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Println("no bearer")
+		w.WriteHeader(401)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+	userID, err := cfg.validateToken(r.Context(), token)
+	if err != nil {
+		log.Printf("jwt error: %v", err)
+		w.WriteHeader(401)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+	limit, err := pagination.ParseLimit(r.URL.Query().Get("limit"))
+	if err != nil {
+		msg := fmt.Sprintf("400 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 400)
+		return
+	}
+	params := database.ListNotificationsParams{
+		UserID:    userID,
+		PageLimit: limit + 1,
+	}
+	if rawCursor := r.URL.Query().Get("cursor"); rawCursor != "" {
+		cursor, err := pagination.DecodeCursor(rawCursor)
+		if err != nil {
+			msg := fmt.Sprintf("400 - %s", err)
+			log.Printf("%s\n", msg)
+			http.Error(w, msg, 400)
+			return
+		}
+		params.AfterCreatedAt = sql.NullTime{Time: cursor.Time, Valid: true}
+		params.AfterID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
+	}
+	notifications, err := cfg.db.ListNotifications(r.Context(), params)
+	if err != nil {
+		msg := fmt.Sprintf("500 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	page := notificationsPage{
+		Notifications: []returnNotification{},
+	}
+	if len(notifications) > int(limit) {
+		notifications = notifications[:limit]
+		last := notifications[len(notifications)-1]
+		page.NextCursor = pagination.Cursor{Time: last.CreatedAt, ID: last.ID}.Encode()
+	}
+	for _, notification := range notifications {
+		page.Notifications = append(page.Notifications, returnNotification{
+			ID:        notification.ID,
+			CreatedAt: notification.CreatedAt,
+			Kind:      notification.Kind,
+			ActorID:   notification.ActorID,
+			ChirpID:   notification.ChirpID,
+			Read:      notification.ReadAt.Valid,
+		})
+	}
+
+	dat, errMarshal := json.Marshal(page)
+	if errMarshal != nil {
+		msg := fmt.Sprintf("500 - %s", errMarshal)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+	w.Write(dat)
+}
+
+func (cfg *apiConfig) readNotifications(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Println("no bearer")
+		w.WriteHeader(401)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+	userID, err := cfg.validateToken(r.Context(), token)
+	if err != nil {
+		log.Printf("jwt error: %v", err)
+		w.WriteHeader(401)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+	err = cfg.db.MarkNotificationsRead(r.Context(), database.MarkNotificationsReadParams{
+		UserID: userID,
+		ReadAt: sql.NullTime{Time: time.Now(), Valid: true},
+	})
+	if err != nil {
+		http.Error(w, http.StatusText(500), 500)
+		return
+	}
+	w.WriteHeader(204)
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/uncomfyhalomacro/chirpy/internal/auth"
+	"github.com/uncomfyhalomacro/chirpy/internal/database"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	minPollOptions      = 2
+	maxPollOptions      = 4
+	maxPollOptionLength = 25
+	maxPollDuration     = 7 * 24 * time.Hour
+)
+
+type pollShape struct {
+	Options  []string  `json:"options"`
+	ClosesAt time.Time `json:"closes_at"`
+}
+
+type returnPoll struct {
+	ClosesAt time.Time          `json:"closes_at"`
+	Closed   bool               `json:"closed"`
+	VotedFor *uuid.UUID         `json:"voted_for,omitempty"`
+	Options  []returnPollOption `json:"options"`
+}
+
+// returnPollOption leaves Votes out until the poll has closed or the viewer
+// has voted, so nobody votes with the crowd.
+type returnPollOption struct {
+	ID    uuid.UUID `json:"id"`
+	Label string    `json:"label"`
+	Votes *int64    `json:"votes,omitempty"`
+}
+
+// checkPoll validates a poll for a chirp that goes out at opensAt and
+// trims its option labels.
+func checkPoll(poll *pollShape, opensAt time.Time) error {
+	if len(poll.Options) < minPollOptions || len(poll.Options) > maxPollOptions {
+		return fmt.Errorf("a poll needs %d to %d options", minPollOptions, maxPollOptions)
+	}
+	seen := make(map[string]bool, len(poll.Options))
+	for i, label := range poll.Options {
+		label = strings.TrimSpace(label)
+		if label == "" {
+			return errors.New("poll options cannot be blank")
+		}
+		if utf8.RuneCountInString(label) > maxPollOptionLength {
+			return fmt.Errorf("poll options can be at most %d characters", maxPollOptionLength)
+		}
+		if seen[strings.ToLower(label)] {
+			return errors.New("poll options should be different from each other")
+		}
+		seen[strings.ToLower(label)] = true
+		poll.Options[i] = label
+	}
+	if !poll.ClosesAt.After(opensAt) {
+		return errors.New("closes_at should be after the chirp is published")
+	}
+	if poll.ClosesAt.Sub(opensAt) > maxPollDuration {
+		return fmt.Errorf("a poll can stay open for at most %s", maxPollDuration)
+	}
+	return nil
+}
+
+func savePoll(ctx context.Context, q *database.Queries, chirpID uuid.UUID, poll *pollShape) error {
+	err := q.CreatePoll(ctx, database.CreatePollParams{
+		ChirpID:  chirpID,
+		ClosesAt: poll.ClosesAt.In(time.Local),
+	})
+	if err != nil {
+		return err
+	}
+	for i, label := range poll.Options {
+		err = q.AddPollOption(ctx, database.AddPollOptionParams{
+			ChirpID:  chirpID,
+			Position: int32(i),
+			Label:    label,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadPolls returns the polls on the given chirps as the viewer should see
+// them. Whether a poll is closed is worked out from closes_at every time,
+// so nothing has to run when a poll closes.
+func (cfg *apiConfig) loadPolls(ctx context.Context, viewerID uuid.NullUUID, ids []uuid.UUID) (map[uuid.UUID]*returnPoll, error) {
+	polls, err := cfg.db.GetPolls(ctx, ids)
+	if err != nil || len(polls) == 0 {
+		return nil, err
+	}
+	pollIDs := make([]uuid.UUID, 0, len(polls))
+	pollsByID := make(map[uuid.UUID]*returnPoll, len(polls))
+	now := time.Now()
+	for _, poll := range polls {
+		pollIDs = append(pollIDs, poll.ChirpID)
+		pollsByID[poll.ChirpID] = &returnPoll{
+			ClosesAt: poll.ClosesAt,
+			Closed:   !now.Before(poll.ClosesAt),
+			Options:  []returnPollOption{},
+		}
+	}
+	results, err := cfg.db.GetPollResults(ctx, database.GetPollResultsParams{
+		ViewerID: viewerID,
+		ChirpIds: pollIDs,
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range results {
+		poll := pollsByID[row.ChirpID]
+		poll.Options = append(poll.Options, returnPollOption{
+			ID:    row.ID,
+			Label: row.Label,
+			Votes: &row.Votes,
+		})
+		if row.VotedByMe {
+			poll.VotedFor = &row.ID
+		}
+	}
+	for _, poll := range pollsByID {
+		if poll.Closed || poll.VotedFor != nil {
+			continue
+		}
+		for i := range poll.Options {
+			poll.Options[i].Votes = nil
+		}
+	}
+	return pollsByID, nil
+}
+
+func (cfg *apiConfig) votePoll(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Println("no bearer")
+		w.WriteHeader(401)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+	userID, err := cfg.validateToken(r.Context(), token)
+	if err != nil {
+		log.Printf("jwt error: %v", err)
+		w.WriteHeader(401)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+	id, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		msg := fmt.Sprintf("400 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 400)
+		return
+	}
+	var params struct {
+		OptionID uuid.UUID `json:"option_id"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	if err = decoder.Decode(&params); err != nil {
+		msg := fmt.Sprintf("400 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 400)
+		return
+	}
+	chirp, err := cfg.db.GetChirp(r.Context(), database.GetChirpParams{
+		ID:       id,
+		ViewerID: uuid.NullUUID{UUID: userID, Valid: true},
+	})
+	if err != nil {
+		msg := fmt.Sprintf("404 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 404)
+		return
+	}
+	poll, err := cfg.db.GetPoll(r.Context(), chirp.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "404 - this chirp has no poll", 404)
+		return
+	}
+	if err != nil {
+		msg := fmt.Sprintf("500 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	options, err := cfg.db.GetPollResults(r.Context(), database.GetPollResultsParams{
+		ViewerID: uuid.NullUUID{UUID: userID, Valid: true},
+		ChirpIds: []uuid.UUID{chirp.ID},
+	})
+	if err != nil {
+		msg := fmt.Sprintf("500 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	validOption := false
+	for _, option := range options {
+		validOption = validOption || option.ID == params.OptionID
+	}
+	if !validOption {
+		http.Error(w, "400 - option_id is not an option of this poll", 400)
+		return
+	}
+
+	now := time.Now()
+	voted, err := cfg.db.VotePoll(r.Context(), database.VotePollParams{
+		UserID:    userID,
+		CreatedAt: now,
+		ChirpID:   chirp.ID,
+		OptionID:  params.OptionID,
+	})
+	if err != nil {
+		msg := fmt.Sprintf("500 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	if voted == 0 {
+		if !now.Before(poll.ClosesAt) {
+			http.Error(w, "409 - this poll has closed", 409)
+			return
+		}
+		http.Error(w, "409 - you already voted in this poll", 409)
+		return
+	}
+
+	respBody, err := cfg.loadChirpJSON(r.Context(), uuid.NullUUID{UUID: userID, Valid: true}, chirp)
+	if err != nil {
+		msg := fmt.Sprintf("500 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	dat, errMarshal := json.Marshal(respBody)
+	if errMarshal != nil {
+		msg := fmt.Sprintf("500 - %s", errMarshal)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+	w.Write(dat)
+}
@@ -0,0 +1,347 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/uncomfyhalomacro/chirpy/internal/auth"
+	"github.com/uncomfyhalomacro/chirpy/internal/database"
+	"github.com/uncomfyhalomacro/chirpy/internal/profanity"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+	"unicode"
+)
+
+const (
+	profanitySourceFile     = "file"
+	profanitySourceDatabase = "database"
+)
+
+// profanityLists is everything loaded by one reload. It is swapped in as a
+// whole so a chirp is never checked against half of a reload.
+type profanityLists struct {
+	filter   *profanity.Filter
+	file     []profanity.List
+	database []profanity.List
+}
+
+type returnProfanityList struct {
+	Name     string             `json:"name"`
+	Locale   string             `json:"locale"`
+	Strategy profanity.Strategy `json:"strategy"`
+	Source   string             `json:"source"`
+	// Enabled is false for lists outside PROFANITY_LOCALES.
+	Enabled bool     `json:"enabled"`
+	Terms   []string `json:"terms"`
+}
+
+// reloadProfanity reads the lists from PROFANITY_FILE and the database and
+// starts using them. On error the lists in use are kept.
+func (cfg *apiConfig) reloadProfanity(ctx context.Context) error {
+	cfg.profanityMu.Lock()
+	defer cfg.profanityMu.Unlock()
+	var lists profanityLists
+	if cfg.profanityFile != "" {
+		fileLists, err := profanity.ReadFile(cfg.profanityFile)
+		if err != nil {
+			return err
+		}
+		lists.file = fileLists
+	}
+	dbLists, err := cfg.db.ListProfanityLists(ctx)
+	if err != nil {
+		return err
+	}
+	terms, err := cfg.db.ListProfanityTerms(ctx)
+	if err != nil {
+		return err
+	}
+	termsByList := map[string][]string{}
+	for _, term := range terms {
+		termsByList[term.ListID.String()] = append(termsByList[term.ListID.String()], term.Term)
+	}
+	for _, list := range dbLists {
+		lists.database = append(lists.database, profanity.List{
+			Name:     list.Name,
+			Locale:   list.Locale,
+			Strategy: profanity.Strategy(list.Strategy),
+			Terms:    termsByList[list.ID.String()],
+		})
+	}
+	lists.filter = profanity.New(append(append([]profanity.List{}, lists.file...), lists.database...), cfg.profanityLocales)
+	cfg.profanity.Store(&lists)
+	log.Printf("loaded %d profanity lists from file and %d from the database\n", len(lists.file), len(lists.database))
+	return nil
+}
+
+// reloadProfanityOnSignal reloads the lists every time the process gets a
+// SIGHUP, until ctx is done.
+func (cfg *apiConfig) reloadProfanityOnSignal(ctx context.Context) {
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+	defer signal.Stop(hangups)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangups:
+			if err := cfg.reloadProfanity(ctx); err != nil {
+				log.Printf("failed to reload profanity lists: %v\n", err)
+			}
+		}
+	}
+}
+
+// adminAuthorized checks the ApiKey header against ADMIN_KEY and writes
+// the error response itself when it does not match. Without an ADMIN_KEY
+// the endpoints that use it are turned off.
+func (cfg *apiConfig) adminAuthorized(w http.ResponseWriter, r *http.Request) bool {
+	if cfg.adminKey == "" {
+		http.Error(w, "403 - ADMIN_KEY is not set", 403)
+		return false
+	}
+	key, err := auth.GetApiKey(r.Header)
+	if err != nil || key != cfg.adminKey {
+		w.WriteHeader(401)
+		w.Write([]byte("Unauthorized"))
+		return false
+	}
+	return true
+}
+
+func (cfg *apiConfig) getProfanityLists(w http.ResponseWriter, r *http.Request) {
+	if !cfg.adminAuthorized(w, r) {
+		return
+	}
+	lists := cfg.profanity.Load()
+	respBody := []returnProfanityList{}
+	for _, source := range []struct {
+		name  string
+		lists []profanity.List
+	}{
+		{profanitySourceFile, lists.file},
+		{profanitySourceDatabase, lists.database},
+	} {
+		for _, list := range source.lists {
+			terms := list.Terms
+			if terms == nil {
+				terms = []string{}
+			}
+			respBody = append(respBody, returnProfanityList{
+				Name:     list.Name,
+				Locale:   list.Locale,
+				Strategy: list.Strategy,
+				Source:   source.name,
+				Enabled:  lists.filter.Enabled(list.Locale),
+				Terms:    terms,
+			})
+		}
+	}
+	dat, errMarshal := json.Marshal(respBody)
+	if errMarshal != nil {
+		msg := fmt.Sprintf("500 - %s", errMarshal)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+	w.Write(dat)
+}
+
+// applyProfanityChange reloads the lists after an admin changed them in
+// the database and writes the response. The change is already saved by
+// then, so a failed reload still answers with status; the failure is
+// logged and the next reload or SIGHUP picks the change up.
+func (cfg *apiConfig) applyProfanityChange(w http.ResponseWriter, r *http.Request, status int, respBody any) {
+	if err := cfg.reloadProfanity(r.Context()); err != nil {
+		log.Printf("failed to reload profanity lists: %v\n", err)
+	}
+	if respBody == nil {
+		w.WriteHeader(status)
+		return
+	}
+	dat, errMarshal := json.Marshal(respBody)
+	if errMarshal != nil {
+		msg := fmt.Sprintf("500 - %s", errMarshal)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(dat)
+}
+
+func (cfg *apiConfig) postProfanityList(w http.ResponseWriter, r *http.Request) {
+	if !cfg.adminAuthorized(w, r) {
+		return
+	}
+	var postData struct {
+		Name     string `json:"name"`
+		Locale   string `json:"locale"`
+		Strategy string `json:"strategy"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&postData)
+	if err != nil {
+		msg := fmt.Sprintf("400 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 400)
+		return
+	}
+	strategy, err := profanity.ParseStrategy(postData.Strategy)
+	if err != nil {
+		msg := fmt.Sprintf("400 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 400)
+		return
+	}
+	if postData.Name == "" || postData.Locale == "" || strings.ContainsFunc(postData.Locale, unicode.IsSpace) {
+		http.Error(w, "400 - a list needs a name and a locale", 400)
+		return
+	}
+	list, err := cfg.db.CreateProfanityList(r.Context(), database.CreateProfanityListParams{
+		CreatedAt: time.Now(),
+		Name:      postData.Name,
+		Locale:    postData.Locale,
+		Strategy:  string(strategy),
+	})
+	if isUniqueViolation(err) {
+		http.Error(w, "409 - a list with this name already exists", 409)
+		return
+	}
+	if err != nil {
+		msg := fmt.Sprintf("500 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	cfg.applyProfanityChange(w, r, 201, returnProfanityList{
+		Name:     list.Name,
+		Locale:   list.Locale,
+		Strategy: strategy,
+		Source:   profanitySourceDatabase,
+		Enabled:  cfg.profanity.Load().filter.Enabled(list.Locale),
+		Terms:    []string{},
+	})
+}
+
+func (cfg *apiConfig) deleteProfanityList(w http.ResponseWriter, r *http.Request) {
+	if !cfg.adminAuthorized(w, r) {
+		return
+	}
+	n, err := cfg.db.DeleteProfanityList(r.Context(), r.PathValue("name"))
+	if err != nil {
+		msg := fmt.Sprintf("500 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	if n == 0 {
+		// lists from PROFANITY_FILE end up here too
+		http.Error(w, "404 - no such list in the database", 404)
+		return
+	}
+	cfg.applyProfanityChange(w, r, 204, nil)
+}
+
+// getDatabaseProfanityList looks up the list named in the path and writes
+// the error response itself when it is not in the database.
+func (cfg *apiConfig) getDatabaseProfanityList(w http.ResponseWriter, r *http.Request) (database.ProfanityList, bool) {
+	list, err := cfg.db.GetProfanityList(r.Context(), r.PathValue("name"))
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "404 - no such list in the database", 404)
+		return list, false
+	}
+	if err != nil {
+		msg := fmt.Sprintf("500 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return list, false
+	}
+	return list, true
+}
+
+func (cfg *apiConfig) postProfanityTerm(w http.ResponseWriter, r *http.Request) {
+	if !cfg.adminAuthorized(w, r) {
+		return
+	}
+	var postData struct {
+		Term string `json:"term"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&postData)
+	if err != nil {
+		msg := fmt.Sprintf("400 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 400)
+		return
+	}
+	term := profanity.NormalizeTerm(postData.Term)
+	if !profanity.ValidTerm(term) {
+		http.Error(w, "400 - a term should be a single word", 400)
+		return
+	}
+	list, ok := cfg.getDatabaseProfanityList(w, r)
+	if !ok {
+		return
+	}
+	err = cfg.db.AddProfanityTerm(r.Context(), database.AddProfanityTermParams{
+		ListID:    list.ID,
+		Term:      term,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		msg := fmt.Sprintf("500 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	cfg.applyProfanityChange(w, r, 204, nil)
+}
+
+func (cfg *apiConfig) deleteProfanityTerm(w http.ResponseWriter, r *http.Request) {
+	if !cfg.adminAuthorized(w, r) {
+		return
+	}
+	list, ok := cfg.getDatabaseProfanityList(w, r)
+	if !ok {
+		return
+	}
+	n, err := cfg.db.RemoveProfanityTerm(r.Context(), database.RemoveProfanityTermParams{
+		ListID: list.ID,
+		Term:   profanity.NormalizeTerm(r.PathValue("term")),
+	})
+	if err != nil {
+		msg := fmt.Sprintf("500 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	if n == 0 {
+		http.Error(w, "404 - the list does not have this term", 404)
+		return
+	}
+	cfg.applyProfanityChange(w, r, 204, nil)
+}
+
+func (cfg *apiConfig) postProfanityReload(w http.ResponseWriter, r *http.Request) {
+	if !cfg.adminAuthorized(w, r) {
+		return
+	}
+	if err := cfg.reloadProfanity(r.Context()); err != nil {
+		msg := fmt.Sprintf("500 - the lists could not be reloaded: %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	w.WriteHeader(204)
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/uncomfyhalomacro/chirpy/internal/auth"
+	"github.com/uncomfyhalomacro/chirpy/internal/database"
+	"log"
+	"net/http"
+	"time"
+)
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+// getRepostTarget looks up the chirp being rechirped or quoted. Reposting
+// a plain rechirp reposts the chirp it points at instead.
+func (cfg *apiConfig) getRepostTarget(r *http.Request, viewerID uuid.UUID) (database.Chirp, error) {
+	id, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		return database.Chirp{}, err
+	}
+	params := database.GetChirpParams{
+		ID:       id,
+		ViewerID: uuid.NullUUID{UUID: viewerID, Valid: true},
+	}
+	chirp, err := cfg.db.GetChirp(r.Context(), params)
+	if err != nil {
+		return chirp, err
+	}
+	if chirp.RechirpOfID.Valid {
+		params.ID = chirp.RechirpOfID.UUID
+		return cfg.db.GetChirp(r.Context(), params)
+	}
+	return chirp, nil
+}
+
+func (cfg *apiConfig) rechirp(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Println("no bearer")
+		w.WriteHeader(401)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+	userID, err := cfg.validateToken(r.Context(), token)
+	if err != nil {
+		log.Printf("jwt error: %v", err)
+		w.WriteHeader(401)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+	perks, ok := cfg.callerEntitlements(w, r, userID)
+	if !ok {
+		return
+	}
+	if !cfg.limiter.Allow(userID, perks.ChirpsPerMinute) {
+		http.Error(w, "429 - you are posting chirps too quickly", 429)
+		return
+	}
+	original, err := cfg.getRepostTarget(r, userID)
+	if err != nil {
+		msg := fmt.Sprintf("404 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 404)
+		return
+	}
+	// reposting would show the chirp to people it was not meant for
+	if original.Visibility != visibilityPublic {
+		http.Error(w, "403 - only public chirps can be reposted", 403)
+		return
+	}
+	now := time.Now()
+	chirp, err := cfg.createChirp(r.Context(), database.CreateChirpParams{
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		UserID:      userID,
+		RechirpOfID: uuid.NullUUID{UUID: original.ID, Valid: true},
+		Visibility:  visibilityPublic,
+	}, nil, nil)
+	if isUniqueViolation(err) {
+		http.Error(w, "409 - you have already rechirped this chirp", 409)
+		return
+	}
+	if err != nil {
+		msg := fmt.Sprintf("500 - %s", err)
+		log.Printf("failed to create rechirp! %s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	respBody, err := cfg.loadChirpJSON(r.Context(), uuid.NullUUID{UUID: userID, Valid: true}, chirp)
+	if err != nil {
+		msg := fmt.Sprintf("500 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	dat, errMarshal := json.Marshal(respBody)
+	if errMarshal != nil {
+		msg := fmt.Sprintf("500 - %s", errMarshal)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(201)
+	w.Write(dat)
+}
+
+func (cfg *apiConfig) undoRechirp(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Println("no bearer")
+		w.WriteHeader(401)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+	userID, err := cfg.validateToken(r.Context(), token)
+	if err != nil {
+		log.Printf("jwt error: %v", err)
+		w.WriteHeader(401)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+	id, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		msg := fmt.Sprintf("400 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 400)
+		return
+	}
+	// resolve the ID like rechirp does, so undoing works on the same ID;
+	// the raw ID is kept when the chirp can no longer be seen
+	if target, err := cfg.getRepostTarget(r, userID); err == nil {
+		id = target.ID
+	}
+	n, err := cfg.db.DeleteRechirp(r.Context(), database.DeleteRechirpParams{
+		UserID:  userID,
+		ChirpID: id,
+	})
+	if err != nil {
+		http.Error(w, http.StatusText(500), 500)
+		return
+	}
+	if n == 0 {
+		http.Error(w, "404 - you have not rechirped this chirp", 404)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) quoteChirp(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Println("no bearer")
+		w.WriteHeader(401)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+	userID, err := cfg.validateToken(r.Context(), token)
+	if err != nil {
+		log.Printf("jwt error: %v", err)
+		w.WriteHeader(401)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+	perks, ok := cfg.callerEntitlements(w, r, userID)
+	if !ok {
+		return
+	}
+	if !cfg.limiter.Allow(userID, perks.ChirpsPerMinute) {
+		http.Error(w, "429 - you are posting chirps too quickly", 429)
+		return
+	}
+	original, err := cfg.getRepostTarget(r, userID)
+	if err != nil {
+		msg := fmt.Sprintf("404 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 404)
+		return
+	}
+	// reposting would show the chirp to people it was not meant for
+	if original.Visibility != visibilityPublic {
+		http.Error(w, "403 - only public chirps can be reposted", 403)
+		return
+	}
+	var postData postDataShape
+	decoder := json.NewDecoder(r.Body)
+	err = decoder.Decode(&postData)
+	if err != nil {
+		msg := fmt.Sprintf("400 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 400)
+		return
+	}
+	cleanedBody, flagged, violations := cfg.cleanChirpBody(postData.Body, perks.MaxChirpLength)
+	if len(violations) > 0 {
+		writeViolations(w, violations)
+		return
+	}
+	visibility, err := parseVisibility(postData.Visibility)
+	if err != nil {
+		msg := fmt.Sprintf("400 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 400)
+		return
+	}
+	contentWarning, err := parseContentWarning(postData.ContentWarning)
+	if err != nil {
+		msg := fmt.Sprintf("400 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 400)
+		return
+	}
+	now := time.Now()
+	chirp, err := cfg.createChirp(r.Context(), database.CreateChirpParams{
+		Body:           cleanedBody,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+		UserID:         userID,
+		QuoteOfID:      uuid.NullUUID{UUID: original.ID, Valid: true},
+		Visibility:     visibility,
+		ContentWarning: contentWarning,
+		Sensitive:      postData.Sensitive || flagged,
+	}, nil, nil)
+	if err != nil {
+		msg := fmt.Sprintf("500 - %s", err)
+		log.Printf("failed to create quote chirp! %s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	respBody, err := cfg.loadChirpJSON(r.Context(), uuid.NullUUID{UUID: userID, Valid: true}, chirp)
+	if err != nil {
+		msg := fmt.Sprintf("500 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	dat, errMarshal := json.Marshal(respBody)
+	if errMarshal != nil {
+		msg := fmt.Sprintf("500 - %s", errMarshal)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(201)
+	w.Write(dat)
+}
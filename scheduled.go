@@ -0,0 +1,346 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/uncomfyhalomacro/chirpy/internal/auth"
+	"github.com/uncomfyhalomacro/chirpy/internal/database"
+	"github.com/uncomfyhalomacro/chirpy/internal/pagination"
+	"log"
+	"net/http"
+	"time"
+)
+
+const (
+	schedulerInterval = 5 * time.Second
+	publishBatchSize  = 100
+)
+
+// runScheduler publishes scheduled chirps as they come due until ctx is
+// done. Pending chirps live in the database, so anything that came due
+// while the server was down goes out on the first tick.
+func (cfg *apiConfig) runScheduler(ctx context.Context) {
+	ticker := time.NewTicker(schedulerInterval)
+	defer ticker.Stop()
+	for {
+		if err := cfg.publishDueChirps(ctx); err != nil {
+			log.Printf("failed to publish scheduled chirps: %v\n", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (cfg *apiConfig) publishDueChirps(ctx context.Context) error {
+	for {
+		processed, err := cfg.publishDueBatch(ctx)
+		if err != nil {
+			return err
+		}
+		if processed < publishBatchSize {
+			return nil
+		}
+	}
+}
+
+// publishDueBatch publishes up to publishBatchSize due chirps, each in its
+// own transaction. A chirp the database rejects is marked so it stops
+// holding up the rest; rescheduling it gives it another try. Any other
+// error is left for the next tick. It returns how many chirps were
+// published or marked, which is less than the batch when some were
+// locked by a reschedule or cancel in progress or failed for now.
+func (cfg *apiConfig) publishDueBatch(ctx context.Context) (int, error) {
+	now := time.Now()
+	due, err := cfg.db.ListDueChirps(ctx, database.ListDueChirpsParams{
+		DueBy:     sql.NullTime{Time: now, Valid: true},
+		PageLimit: publishBatchSize,
+	})
+	if err != nil {
+		return 0, err
+	}
+	processed := 0
+	for _, pending := range due {
+		published, err := cfg.publishDueChirp(ctx, pending.ID, now)
+		if err != nil && !isChirpError(err) {
+			log.Printf("failed to publish chirp %s, will retry: %v\n", pending.ID, err)
+			continue
+		}
+		if err != nil {
+			log.Printf("failed to publish chirp %s: %v\n", pending.ID, err)
+			err = cfg.db.MarkChirpPublishFailed(ctx, database.MarkChirpPublishFailedParams{
+				ID:       pending.ID,
+				FailedAt: sql.NullTime{Time: time.Now(), Valid: true},
+			})
+			if err != nil {
+				return processed, err
+			}
+			published = true
+		}
+		if published {
+			processed++
+		}
+	}
+	return processed, nil
+}
+
+// isChirpError reports whether the database rejected the data of a
+// chirp (a data exception or a constraint violation), so trying again
+// would fail the same way.
+func isChirpError(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+	class := pqErr.Code.Class()
+	return class == "22" || class == "23"
+}
+
+// publishDueChirp publishes one chirp. The row stays locked until it
+// commits, so a reschedule or cancel racing with it waits and then finds
+// the chirp already published. It reports false when the chirp was
+// skipped because someone else holds the lock.
+func (cfg *apiConfig) publishDueChirp(ctx context.Context, id uuid.UUID, now time.Time) (bool, error) {
+	tx, err := cfg.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+	_, err = qtx.LockDueChirp(ctx, database.LockDueChirpParams{
+		ID:    id,
+		DueBy: sql.NullTime{Time: now, Valid: true},
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	chirp, err := qtx.PublishChirp(ctx, database.PublishChirpParams{
+		ID:          id,
+		PublishedAt: now,
+	})
+	if err != nil {
+		return false, err
+	}
+	if _, err = cfg.saveChirpEntities(ctx, qtx, chirp); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+func (cfg *apiConfig) getScheduledChirps(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Println("no bearer")
+		w.WriteHeader(401)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+	userID, err := cfg.validateToken(r.Context(), token)
+	if err != nil {
+		log.Printf("jwt error: %v", err)
+		w.WriteHeader(401)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+	limit, err := pagination.ParseLimit(r.URL.Query().Get("limit"))
+	if err != nil {
+		msg := fmt.Sprintf("400 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 400)
+		return
+	}
+	params := database.ListScheduledChirpsParams{
+		UserID:    userID,
+		PageLimit: limit + 1,
+	}
+	if rawCursor := r.URL.Query().Get("cursor"); rawCursor != "" {
+		cursor, err := pagination.DecodeCursor(rawCursor)
+		if err != nil {
+			msg := fmt.Sprintf("400 - %s", err)
+			log.Printf("%s\n", msg)
+			http.Error(w, msg, 400)
+			return
+		}
+		params.AfterPublishAt = sql.NullTime{Time: cursor.Time, Valid: true}
+		params.AfterID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
+	}
+	chirps, err := cfg.db.ListScheduledChirps(r.Context(), params)
+	if err != nil {
+		msg := fmt.Sprintf("500 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	var page chirpsPage
+	if len(chirps) > int(limit) {
+		chirps = chirps[:limit]
+		last := chirps[len(chirps)-1]
+		page.NextCursor = pagination.Cursor{Time: last.PublishAt.Time, ID: last.ID}.Encode()
+	}
+	page.Chirps, err = cfg.loadChirpsJSON(r.Context(), uuid.NullUUID{UUID: userID, Valid: true}, chirps)
+	if err != nil {
+		msg := fmt.Sprintf("500 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+
+	dat, errMarshal := json.Marshal(page)
+	if errMarshal != nil {
+		msg := fmt.Sprintf("500 - %s", errMarshal)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+	w.Write(dat)
+}
+
+// getOwnScheduledChirp loads the pending chirp named in the path and
+// writes the error response itself when the caller cannot manage it.
+func (cfg *apiConfig) getOwnScheduledChirp(w http.ResponseWriter, r *http.Request, userID uuid.UUID) (database.Chirp, bool) {
+	id, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		msg := fmt.Sprintf("400 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 400)
+		return database.Chirp{}, false
+	}
+	chirp, err := cfg.db.GetScheduledChirp(r.Context(), id)
+	if err != nil {
+		msg := fmt.Sprintf("404 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 404)
+		return chirp, false
+	}
+	if chirp.UserID.UUID != userID {
+		http.Error(w, http.StatusText(403), 403)
+		return chirp, false
+	}
+	return chirp, true
+}
+
+func (cfg *apiConfig) rescheduleChirp(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Println("no bearer")
+		w.WriteHeader(401)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+	userID, err := cfg.validateToken(r.Context(), token)
+	if err != nil {
+		log.Printf("jwt error: %v", err)
+		w.WriteHeader(401)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+	chirp, ok := cfg.getOwnScheduledChirp(w, r, userID)
+	if !ok {
+		return
+	}
+	var postData postDataShape
+	decoder := json.NewDecoder(r.Body)
+	err = decoder.Decode(&postData)
+	if err != nil {
+		msg := fmt.Sprintf("400 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 400)
+		return
+	}
+	if postData.PublishAt == nil || !postData.PublishAt.After(time.Now()) {
+		http.Error(w, "400 - publish_at should be in the future", 400)
+		return
+	}
+	poll, err := cfg.db.GetPoll(r.Context(), chirp.ID)
+	if err == nil && !poll.ClosesAt.After(*postData.PublishAt) {
+		http.Error(w, "400 - the chirp's poll would close before it is published", 400)
+		return
+	}
+	if err != nil && err != sql.ErrNoRows {
+		msg := fmt.Sprintf("500 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	chirp, err = cfg.db.RescheduleChirp(r.Context(), database.RescheduleChirpParams{
+		ID:        chirp.ID,
+		PublishAt: sql.NullTime{Time: postData.PublishAt.In(time.Local), Valid: true},
+		UpdatedAt: time.Now(),
+	})
+	if err == sql.ErrNoRows {
+		http.Error(w, "409 - this chirp has already been published", 409)
+		return
+	}
+	if err != nil {
+		msg := fmt.Sprintf("500 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	respBody, err := cfg.loadChirpJSON(r.Context(), uuid.NullUUID{UUID: userID, Valid: true}, chirp)
+	if err != nil {
+		msg := fmt.Sprintf("500 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	dat, errMarshal := json.Marshal(respBody)
+	if errMarshal != nil {
+		msg := fmt.Sprintf("500 - %s", errMarshal)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+	w.Write(dat)
+}
+
+func (cfg *apiConfig) cancelScheduledChirp(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Println("no bearer")
+		w.WriteHeader(401)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+	userID, err := cfg.validateToken(r.Context(), token)
+	if err != nil {
+		log.Printf("jwt error: %v", err)
+		w.WriteHeader(401)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+	chirp, ok := cfg.getOwnScheduledChirp(w, r, userID)
+	if !ok {
+		return
+	}
+	attachments, err := cfg.db.GetChirpAttachments(r.Context(), []uuid.UUID{chirp.ID})
+	if err != nil {
+		http.Error(w, http.StatusText(500), 500)
+		return
+	}
+	cancelled, err := cfg.db.CancelScheduledChirp(r.Context(), chirp.ID)
+	if err != nil {
+		http.Error(w, http.StatusText(500), 500)
+		return
+	}
+	if cancelled == 0 {
+		http.Error(w, "409 - this chirp has already been published", 409)
+		return
+	}
+	cfg.removeAttachmentFiles(attachments)
+	w.WriteHeader(204)
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/uncomfyhalomacro/chirpy/internal/auth"
+	"github.com/uncomfyhalomacro/chirpy/internal/database"
+	"log"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+const maxContentWarningLength = 100
+
+type preferencesShape struct {
+	ExpandSensitive bool `json:"expand_sensitive"`
+}
+
+// parseContentWarning checks a content warning from a request. A blank
+// one means the chirp has none.
+func parseContentWarning(contentWarning string) (sql.NullString, error) {
+	contentWarning = strings.TrimSpace(contentWarning)
+	if contentWarning == "" {
+		return sql.NullString{}, nil
+	}
+	if utf8.RuneCountInString(contentWarning) > maxContentWarningLength {
+		return sql.NullString{}, fmt.Errorf("content_warning can be at most %d characters", maxContentWarningLength)
+	}
+	return sql.NullString{String: contentWarning, Valid: true}, nil
+}
+
+// expandSensitive reports whether the viewer asked to see chirps with a
+// content warning or the sensitive flag in full. Everyone else, including
+// anonymous readers, gets them collapsed.
+func (cfg *apiConfig) expandSensitive(ctx context.Context, viewerID uuid.NullUUID) (bool, error) {
+	if !viewerID.Valid {
+		return false, nil
+	}
+	user, err := cfg.db.GetUserByID(ctx, viewerID.UUID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return user.ExpandSensitive, err
+}
+
+// collapseChirp leaves only the content warning and the flag of a chirp
+// the viewer should not see in full. Authors always see their own chirps.
+func collapseChirp(respBody *returnValidChirp, viewerID uuid.NullUUID, expand bool) {
+	if !respBody.Sensitive && respBody.ContentWarning == nil {
+		return
+	}
+	if expand || (viewerID.Valid && respBody.UserID != nil && viewerID.UUID == *respBody.UserID) {
+		return
+	}
+	respBody.Body = ""
+	respBody.Mentions = []returnMention{}
+	respBody.Attachments = []returnAttachment{}
+	respBody.Poll = nil
+	respBody.Collapsed = true
+}
+
+func (cfg *apiConfig) flagChirpSensitive(w http.ResponseWriter, r *http.Request) {
+	cfg.setChirpSensitive(w, r, true)
+}
+
+func (cfg *apiConfig) unflagChirpSensitive(w http.ResponseWriter, r *http.Request) {
+	cfg.setChirpSensitive(w, r, false)
+}
+
+// setChirpSensitive lets a moderator apply or remove the sensitive flag on
+// anyone's chirp. The content warning stays the author's.
+func (cfg *apiConfig) setChirpSensitive(w http.ResponseWriter, r *http.Request, sensitive bool) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Println("no bearer")
+		w.WriteHeader(401)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+	userID, err := cfg.validateToken(r.Context(), token)
+	if err != nil {
+		log.Printf("jwt error: %v", err)
+		w.WriteHeader(401)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		log.Printf("%v\n", err)
+		http.Error(w, "Unauthorized", 401)
+		return
+	}
+	if !user.IsModerator {
+		http.Error(w, "403 - only moderators can flag chirps", 403)
+		return
+	}
+	id, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		msg := fmt.Sprintf("400 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 400)
+		return
+	}
+	chirp, err := cfg.db.SetChirpSensitive(r.Context(), database.SetChirpSensitiveParams{
+		ID:        id,
+		Sensitive: sensitive,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "404 - chirp not found", 404)
+		return
+	}
+	if err != nil {
+		msg := fmt.Sprintf("500 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	respBody, err := cfg.loadChirpJSON(r.Context(), uuid.NullUUID{UUID: userID, Valid: true}, chirp)
+	if err != nil {
+		msg := fmt.Sprintf("500 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	dat, errMarshal := json.Marshal(respBody)
+	if errMarshal != nil {
+		msg := fmt.Sprintf("500 - %s", errMarshal)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+	w.Write(dat)
+}
+
+func writePreferences(w http.ResponseWriter, user database.User) {
+	dat, errMarshal := json.Marshal(preferencesShape{ExpandSensitive: user.ExpandSensitive})
+	if errMarshal != nil {
+		msg := fmt.Sprintf("500 - %s", errMarshal)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+	w.Write(dat)
+}
+
+func (cfg *apiConfig) getPreferences(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Println("no bearer")
+		w.WriteHeader(401)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+	userID, err := cfg.validateToken(r.Context(), token)
+	if err != nil {
+		log.Printf("jwt error: %v", err)
+		w.WriteHeader(401)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		log.Printf("%v\n", err)
+		http.Error(w, "Unauthorized", 401)
+		return
+	}
+	writePreferences(w, user)
+}
+
+func (cfg *apiConfig) putPreferences(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Println("no bearer")
+		w.WriteHeader(401)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+	userID, err := cfg.validateToken(r.Context(), token)
+	if err != nil {
+		log.Printf("jwt error: %v", err)
+		w.WriteHeader(401)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+	var postData preferencesShape
+	decoder := json.NewDecoder(r.Body)
+	err = decoder.Decode(&postData)
+	if err != nil {
+		msg := fmt.Sprintf("400 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 400)
+		return
+	}
+	user, err := cfg.db.SetExpandSensitive(r.Context(), database.SetExpandSensitiveParams{
+		ID:              userID,
+		ExpandSensitive: postData.ExpandSensitive,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Unauthorized", 401)
+		return
+	}
+	if err != nil {
+		msg := fmt.Sprintf("500 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	writePreferences(w, user)
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/uncomfyhalomacro/chirpy/internal/auth"
+	"github.com/uncomfyhalomacro/chirpy/internal/database"
+	"log"
+	"net/http"
+	"time"
+)
+
+const (
+	defaultRestoreWindow = 30 * 24 * time.Hour
+	purgeInterval        = 10 * time.Minute
+	purgeBatchSize       = 100
+)
+
+func (cfg *apiConfig) restoreChirp(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		log.Println("no bearer")
+		w.WriteHeader(401)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+	userID, err := cfg.validateToken(r.Context(), token)
+	if err != nil {
+		log.Printf("jwt error: %v", err)
+		w.WriteHeader(401)
+		w.Write([]byte("Unauthorized"))
+		return
+	}
+	id, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		msg := fmt.Sprintf("400 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 400)
+		return
+	}
+	chirp, err := cfg.db.GetDeletedChirp(r.Context(), id)
+	if err != nil {
+		msg := fmt.Sprintf("404 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 404)
+		return
+	}
+	if chirp.UserID.UUID != userID {
+		http.Error(w, http.StatusText(403), 403)
+		return
+	}
+	if time.Since(chirp.DeletedAt.Time) > cfg.restoreWindow {
+		// the purger has not caught up with it yet but it is gone all
+		// the same
+		http.Error(w, "410 - the restore window for this chirp has passed", 410)
+		return
+	}
+	chirp, err = cfg.db.RestoreChirp(r.Context(), chirp.ID)
+	if isUniqueViolation(err) {
+		http.Error(w, "409 - you have rechirped this chirp again since deleting it", 409)
+		return
+	}
+	if err != nil {
+		msg := fmt.Sprintf("500 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	respBody, err := cfg.loadChirpJSON(r.Context(), uuid.NullUUID{UUID: userID, Valid: true}, chirp)
+	if err != nil {
+		msg := fmt.Sprintf("500 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	dat, errMarshal := json.Marshal(respBody)
+	if errMarshal != nil {
+		msg := fmt.Sprintf("500 - %s", errMarshal)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+	w.Write(dat)
+}
+
+// purgeDeletedChirps runs until ctx is done, permanently removing chirps
+// whose restore window has passed.
+func (cfg *apiConfig) purgeDeletedChirps(ctx context.Context) {
+	ticker := time.NewTicker(purgeInterval)
+	defer ticker.Stop()
+	for {
+		if err := cfg.purgeExpiredChirps(ctx); err != nil {
+			log.Printf("failed to purge deleted chirps: %v\n", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (cfg *apiConfig) purgeExpiredChirps(ctx context.Context) error {
+	for {
+		chirps, err := cfg.db.ListPurgeableChirps(ctx, database.ListPurgeableChirpsParams{
+			DeletedBefore: sql.NullTime{Time: time.Now().Add(-cfg.restoreWindow), Valid: true},
+			PageLimit:     purgeBatchSize,
+		})
+		if err != nil {
+			return err
+		}
+		for _, chirp := range chirps {
+			if err = cfg.purgeChirp(ctx, chirp.ID); err != nil {
+				return err
+			}
+		}
+		if len(chirps) < purgeBatchSize {
+			return nil
+		}
+	}
+}
+
+// purgeChirp permanently removes a chirp. A chirp that still has replies
+// is turned into a tombstone instead so the thread keeps its shape.
+func (cfg *apiConfig) purgeChirp(ctx context.Context, id uuid.UUID) error {
+	hasReplies, err := cfg.db.ChirpHasReplies(ctx, id)
+	if err != nil {
+		return err
+	}
+	if hasReplies {
+		return cfg.tombstoneChirp(ctx, id)
+	}
+	attachments, err := cfg.db.GetChirpAttachments(ctx, []uuid.UUID{id})
+	if err != nil {
+		return err
+	}
+	if err = cfg.db.DeleteChirp(ctx, id); err != nil {
+		return err
+	}
+	cfg.removeAttachmentFiles(attachments)
+	return nil
+}
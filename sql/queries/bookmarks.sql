@@ -0,0 +1,23 @@
+-- name: AddBookmark :exec
+INSERT INTO bookmarks(user_id, chirp_id, created_at)
+VALUES (
+	$1,
+	$2,
+	$3
+)
+ON CONFLICT (user_id, chirp_id) DO NOTHING;
+
+-- name: RemoveBookmark :exec
+DELETE FROM bookmarks
+WHERE user_id=$1 AND chirp_id=$2;
+
+-- name: ListBookmarks :many
+SELECT sqlc.embed(chirps), bookmarks.created_at AS bookmarked_at
+FROM bookmarks
+JOIN chirps ON chirps.id=bookmarks.chirp_id
+WHERE bookmarks.user_id=sqlc.arg('user_id')
+AND NOT chirps.is_tombstone AND chirps.deleted_at IS NULL
+AND (sqlc.narg('after_bookmarked_at')::timestamp IS NULL OR (bookmarks.created_at, chirps.id) < (sqlc.narg('after_bookmarked_at'), sqlc.narg('after_id')::uuid))
+AND chirp_visible_to(chirps.visibility, chirps.user_id, sqlc.arg('user_id'))
+ORDER BY bookmarks.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg('page_limit');
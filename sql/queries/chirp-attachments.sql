@@ -0,0 +1,30 @@
+-- name: AddChirpAttachment :one
+INSERT INTO chirp_attachments(created_at, chirp_id, position, content_type, width, height)
+VALUES (
+	$1,
+	$2,
+	$3,
+	$4,
+	$5,
+	$6
+)
+RETURNING *;
+
+-- name: GetChirpAttachment :one
+SELECT * FROM chirp_attachments
+WHERE id=$1 LIMIT 1;
+
+-- name: GetChirpAttachments :many
+SELECT * FROM chirp_attachments
+WHERE chirp_id=ANY(sqlc.arg('chirp_ids')::uuid[])
+ORDER BY chirp_id, position;
+
+-- name: DeleteChirpAttachments :many
+DELETE FROM chirp_attachments
+WHERE chirp_id=$1
+RETURNING *;
+
+-- name: ListUserAttachments :many
+SELECT chirp_attachments.* FROM chirp_attachments
+JOIN chirps ON chirps.id=chirp_attachments.chirp_id
+WHERE chirps.user_id=$1;
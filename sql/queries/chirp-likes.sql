@@ -0,0 +1,33 @@
+-- name: LikeChirp :exec
+INSERT INTO chirp_likes(user_id, chirp_id, created_at)
+VALUES (
+	$1,
+	$2,
+	$3
+)
+ON CONFLICT (user_id, chirp_id) DO NOTHING;
+
+-- name: UnlikeChirp :exec
+DELETE FROM chirp_likes
+WHERE user_id=$1 AND chirp_id=$2;
+
+-- name: GetLikeStats :many
+SELECT chirp_id, COUNT(*) AS like_count, COALESCE(BOOL_OR(user_id=sqlc.narg('viewer_id')::uuid), false)::boolean AS liked_by_me
+FROM chirp_likes
+WHERE chirp_id=ANY(sqlc.arg('chirp_ids')::uuid[])
+GROUP BY chirp_id;
+
+-- name: ListLikedChirps :many
+SELECT sqlc.embed(chirps), chirp_likes.created_at AS liked_at
+FROM chirp_likes
+JOIN chirps ON chirps.id=chirp_likes.chirp_id
+WHERE chirp_likes.user_id=sqlc.arg('user_id')
+AND NOT chirps.is_tombstone AND chirps.deleted_at IS NULL
+AND (sqlc.narg('after_liked_at')::timestamp IS NULL OR (chirp_likes.created_at, chirps.id) < (sqlc.narg('after_liked_at'), sqlc.narg('after_id')::uuid))
+AND chirp_visible_to(chirps.visibility, chirps.user_id, sqlc.narg('viewer_id')::uuid)
+ORDER BY chirp_likes.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg('page_limit');
+
+-- name: DeleteChirpLikes :exec
+DELETE FROM chirp_likes
+WHERE chirp_id=$1;
@@ -0,0 +1,19 @@
+-- name: AddChirpMention :exec
+INSERT INTO chirp_mentions(chirp_id, user_id, start_offset, end_offset)
+VALUES (
+	$1,
+	$2,
+	$3,
+	$4
+);
+
+-- name: DeleteChirpMentions :exec
+DELETE FROM chirp_mentions
+WHERE chirp_id=$1;
+
+-- name: GetChirpMentions :many
+SELECT chirp_mentions.*, users.handle::text AS handle
+FROM chirp_mentions
+JOIN users ON users.id=chirp_mentions.user_id
+WHERE chirp_mentions.chirp_id=ANY(sqlc.arg('chirp_ids')::uuid[])
+ORDER BY chirp_mentions.chirp_id, chirp_mentions.start_offset;
@@ -0,0 +1,18 @@
+-- name: AddChirpRevision :one
+INSERT INTO chirp_revisions(chirp_id, body, created_at, replaced_at)
+VALUES (
+	$1,
+	$2,
+	$3,
+	$4
+)
+RETURNING *;
+
+-- name: GetChirpRevisions :many
+SELECT * FROM chirp_revisions
+WHERE chirp_id=$1
+ORDER BY replaced_at ASC;
+
+-- name: DeleteChirpRevisions :exec
+DELETE FROM chirp_revisions
+WHERE chirp_id=$1;
@@ -1,26 +1,198 @@
 -- name: CreateChirp :one
-INSERT INTO chirps(body, created_at, updated_at, user_id)
+INSERT INTO chirps(body, created_at, updated_at, user_id, parent_chirp_id, rechirp_of_id, quote_of_id, publish_at, visibility, content_warning, sensitive)
 VALUES (
 	$1,
 	$2,
 	$3,
-	$4
+	$4,
+	$5,
+	$6,
+	$7,
+	$8,
+	$9,
+	$10,
+	$11
 )
 RETURNING *;
 
--- name: GetChirps :many
+-- name: GetChirp :one
 SELECT * FROM chirps
-ORDER BY created_at ASC;
+WHERE id=sqlc.arg('id') AND NOT is_tombstone AND deleted_at IS NULL AND publish_at IS NULL
+AND chirp_visible_to(chirps.visibility, chirps.user_id, sqlc.narg('viewer_id')::uuid)
+LIMIT 1;
 
--- name: GetChirp :one
+-- name: GetChirpsByIDs :many
+SELECT * FROM chirps
+WHERE id=ANY(sqlc.arg('ids')::uuid[])
+AND deleted_at IS NULL AND publish_at IS NULL
+AND chirp_visible_to(chirps.visibility, chirps.user_id, sqlc.narg('viewer_id')::uuid);
+
+-- name: GetChirpForUpdate :one
 SELECT * FROM chirps
-WHERE id=$1 LIMIT 1;
+WHERE id=$1 AND NOT is_tombstone AND deleted_at IS NULL AND publish_at IS NULL LIMIT 1
+FOR UPDATE;
+
+-- name: UpdateChirpBody :one
+UPDATE chirps
+SET body=$2, updated_at=$3, edited_at=$3
+WHERE id=$1
+RETURNING *;
+
+-- name: SetChirpBody :one
+UPDATE chirps
+SET body=$2
+WHERE id=$1
+RETURNING *;
 
 -- name: DeleteChirp :exec
 DELETE FROM chirps
 WHERE id=$1;
 
--- name: GetChirpsByUserID :many
+-- name: DeleteRechirp :execrows
+DELETE FROM chirps
+WHERE user_id=sqlc.arg('user_id') AND rechirp_of_id=sqlc.arg('chirp_id')::uuid
+AND deleted_at IS NULL;
+
+-- name: SoftDeleteChirp :exec
+UPDATE chirps
+SET deleted_at=$2
+WHERE id=$1;
+
+-- name: GetDeletedChirp :one
+SELECT * FROM chirps
+WHERE id=$1 AND deleted_at IS NOT NULL LIMIT 1;
+
+-- name: RestoreChirp :one
+UPDATE chirps
+SET deleted_at=NULL
+WHERE id=$1
+RETURNING *;
+
+-- name: ListPurgeableChirps :many
+SELECT * FROM chirps
+WHERE deleted_at < sqlc.arg('deleted_before')
+ORDER BY deleted_at ASC
+LIMIT sqlc.arg('page_limit');
+
+-- name: GetRechirpCounts :many
+SELECT rechirp_of_id::uuid AS chirp_id, COUNT(*) AS rechirp_count
+FROM chirps
+WHERE rechirp_of_id=ANY(sqlc.arg('chirp_ids')::uuid[])
+AND deleted_at IS NULL
+GROUP BY rechirp_of_id;
+
+-- name: ChirpHasReplies :one
+SELECT EXISTS(
+	SELECT 1 FROM chirps
+	WHERE parent_chirp_id=sqlc.arg('id')::uuid
+);
+
+-- name: TombstoneChirp :exec
+UPDATE chirps
+SET body='', is_tombstone=true, deleted_at=NULL, content_warning=NULL, sensitive=false
+WHERE id=$1;
+
+-- name: ListRepliedUserChirps :many
+-- The chirps of a user that have replies and are not tombstones yet.
+SELECT * FROM chirps
+WHERE user_id=$1 AND NOT is_tombstone
+AND EXISTS (SELECT 1 FROM chirps AS replies WHERE replies.parent_chirp_id=chirps.id);
+
+-- name: DisownTombstones :exec
+-- Lets the tombstones of a user outlive the account instead of going
+-- with it.
+UPDATE chirps
+SET user_id=NULL
+WHERE user_id=$1 AND is_tombstone;
+
+-- name: ListChirps :many
+-- Every filter is skipped when its argument is NULL, so one query serves
+-- plain listing, filtering and search. sort_key is created_at, updated_at
+-- or rank; rank is only meaningful together with a query and always sorts
+-- descending.
+SELECT sqlc.embed(chirps), COALESCE(ts_rank(to_tsvector('english', body), websearch_to_tsquery('english', sqlc.narg('query')::text))::real, 0)::real AS rank
+FROM chirps
+WHERE NOT is_tombstone AND deleted_at IS NULL AND publish_at IS NULL
+AND (sqlc.narg('query')::text IS NULL OR to_tsvector('english', body) @@ websearch_to_tsquery('english', sqlc.narg('query')::text))
+AND (COALESCE(cardinality(sqlc.narg('author_ids')::uuid[]), 0)=0 OR user_id=ANY(sqlc.narg('author_ids')::uuid[]))
+AND (sqlc.narg('since')::timestamp IS NULL OR created_at >= sqlc.narg('since'))
+AND (sqlc.narg('until')::timestamp IS NULL OR created_at < sqlc.narg('until'))
+AND (sqlc.narg('has_media')::boolean IS NULL OR EXISTS (
+	SELECT 1 FROM chirp_attachments WHERE chirp_attachments.chirp_id=chirps.id
+)=sqlc.narg('has_media'))
+AND (sqlc.narg('min_likes')::int IS NULL OR (
+	SELECT COUNT(*) FROM chirp_likes WHERE chirp_likes.chirp_id=chirps.id
+) >= sqlc.narg('min_likes'))
+AND chirp_visible_to(chirps.visibility, chirps.user_id, sqlc.narg('viewer_id')::uuid)
+AND (sqlc.narg('after_id')::uuid IS NULL OR CASE
+	WHEN sqlc.arg('sort_key')::text='rank'
+		THEN (COALESCE(ts_rank(to_tsvector('english', body), websearch_to_tsquery('english', sqlc.narg('query')::text))::real, 0)::real, created_at, id) < (sqlc.narg('after_rank')::real, sqlc.narg('after_time')::timestamp, sqlc.narg('after_id'))
+	WHEN sqlc.arg('sort_key')='updated_at' AND sqlc.arg('descending')::boolean
+		THEN (updated_at, id) < (sqlc.narg('after_time'), sqlc.narg('after_id'))
+	WHEN sqlc.arg('sort_key')='updated_at'
+		THEN (updated_at, id) > (sqlc.narg('after_time'), sqlc.narg('after_id'))
+	WHEN sqlc.arg('descending')
+		THEN (created_at, id) < (sqlc.narg('after_time'), sqlc.narg('after_id'))
+	ELSE (created_at, id) > (sqlc.narg('after_time'), sqlc.narg('after_id'))
+END)
+ORDER BY
+	CASE WHEN sqlc.arg('sort_key')='rank' THEN COALESCE(ts_rank(to_tsvector('english', body), websearch_to_tsquery('english', sqlc.narg('query')::text))::real, 0)::real END DESC,
+	CASE WHEN sqlc.arg('sort_key') IN ('rank', 'created_at') AND sqlc.arg('descending') THEN created_at END DESC,
+	CASE WHEN sqlc.arg('sort_key')='created_at' AND NOT sqlc.arg('descending') THEN created_at END ASC,
+	CASE WHEN sqlc.arg('sort_key')='updated_at' AND sqlc.arg('descending') THEN updated_at END DESC,
+	CASE WHEN sqlc.arg('sort_key')='updated_at' AND NOT sqlc.arg('descending') THEN updated_at END ASC,
+	CASE WHEN sqlc.arg('descending') THEN id END DESC,
+	CASE WHEN NOT sqlc.arg('descending') THEN id END ASC
+LIMIT sqlc.arg('page_limit');
+
+-- name: GetChirpAncestors :many
+-- Deleted and tombstoned ancestors are kept so the chain has no gaps; the
+-- caller shows them as tombstones.
+WITH RECURSIVE ancestors(id, parent_chirp_id, depth) AS (
+	SELECT c.id, c.parent_chirp_id, 1 FROM chirps AS c
+	WHERE c.id=(SELECT parent_chirp_id FROM chirps WHERE chirps.id=sqlc.arg('id')::uuid)
+	UNION ALL
+	SELECT c.id, c.parent_chirp_id, ancestors.depth + 1 FROM chirps AS c
+	JOIN ancestors ON c.id=ancestors.parent_chirp_id
+)
+SELECT chirps.* FROM chirps
+JOIN ancestors ON chirps.id=ancestors.id
+WHERE chirps.publish_at IS NULL
+AND chirp_visible_to(chirps.visibility, chirps.user_id, sqlc.narg('viewer_id')::uuid)
+ORDER BY ancestors.depth DESC;
+
+-- name: ListChirpReplies :many
+-- Like GetChirpAncestors this keeps deleted replies, so their own replies
+-- stay reachable.
 SELECT * FROM chirps
-WHERE user_id=$1
-ORDER BY created_at ASC;
+WHERE parent_chirp_id=sqlc.arg('parent_id')::uuid
+AND publish_at IS NULL
+AND (sqlc.narg('after_created_at')::timestamp IS NULL OR (created_at, id) > (sqlc.narg('after_created_at'), sqlc.narg('after_id')::uuid))
+AND chirp_visible_to(chirps.visibility, chirps.user_id, sqlc.narg('viewer_id')::uuid)
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg('page_limit');
+
+-- name: GetChirpDescendants :many
+-- The walk goes through deleted and tombstoned replies for the same
+-- reason as ListChirpReplies.
+WITH RECURSIVE descendants(id, depth) AS (
+	SELECT c.id, 1 FROM chirps AS c
+	WHERE c.parent_chirp_id=ANY(sqlc.arg('root_ids')::uuid[])
+	AND c.publish_at IS NULL
+	UNION ALL
+	SELECT c.id, descendants.depth + 1 FROM chirps AS c
+	JOIN descendants ON c.parent_chirp_id=descendants.id
+	WHERE c.publish_at IS NULL
+	AND descendants.depth < sqlc.arg('max_depth')::int
+)
+SELECT chirps.* FROM chirps
+JOIN descendants ON chirps.id=descendants.id
+WHERE chirp_visible_to(chirps.visibility, chirps.user_id, sqlc.narg('viewer_id')::uuid)
+ORDER BY chirps.created_at ASC, chirps.id ASC
+LIMIT sqlc.arg('max_rows');
+
+-- name: SetChirpSensitive :one
+UPDATE chirps
+SET sensitive=$2
+WHERE id=$1 AND deleted_at IS NULL AND NOT is_tombstone
+RETURNING *;
@@ -0,0 +1,35 @@
+-- name: CreateDraft :one
+INSERT INTO drafts(created_at, updated_at, body, user_id)
+VALUES (
+	$1,
+	$2,
+	$3,
+	$4
+)
+RETURNING *;
+
+-- name: GetDraft :one
+SELECT * FROM drafts
+WHERE id=$1 AND user_id=$2 LIMIT 1;
+
+-- name: ListDrafts :many
+SELECT * FROM drafts
+WHERE user_id=sqlc.arg('user_id')
+AND (sqlc.narg('after_updated_at')::timestamp IS NULL OR (updated_at, id) < (sqlc.narg('after_updated_at'), sqlc.narg('after_id')::uuid))
+ORDER BY updated_at DESC, id DESC
+LIMIT sqlc.arg('page_limit');
+
+-- name: UpdateDraft :one
+UPDATE drafts
+SET body=$3, updated_at=$4
+WHERE id=$1 AND user_id=$2
+RETURNING *;
+
+-- name: DeleteDraft :execrows
+DELETE FROM drafts
+WHERE id=$1 AND user_id=$2;
+
+-- name: TakeDraft :one
+DELETE FROM drafts
+WHERE id=$1 AND user_id=$2
+RETURNING *;
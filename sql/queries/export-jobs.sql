@@ -0,0 +1,45 @@
+-- name: CreateExportJob :one
+INSERT INTO export_jobs(created_at, user_id)
+VALUES (
+	$1,
+	$2
+)
+RETURNING *;
+
+-- name: GetLatestExportJob :one
+SELECT * FROM export_jobs
+WHERE user_id=$1
+ORDER BY created_at DESC
+LIMIT 1;
+
+-- name: ClaimExportJob :one
+UPDATE export_jobs
+SET status='running', started_at=sqlc.arg('started_at')
+WHERE id=(
+	SELECT id FROM export_jobs
+	WHERE status='pending'
+	OR (status='running' AND started_at < sqlc.arg('stale_before'))
+	ORDER BY created_at
+	LIMIT 1
+	FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: FinishExportJob :exec
+UPDATE export_jobs
+SET status=$2, finished_at=$3, expires_at=$4
+WHERE id=$1;
+
+-- name: DeleteExpiredExportJobs :many
+DELETE FROM export_jobs
+WHERE expires_at < $1
+RETURNING *;
+
+-- name: ListChirpsForExport :many
+SELECT * FROM chirps
+WHERE user_id=$1 AND NOT is_tombstone
+ORDER BY created_at ASC, id ASC;
+
+-- name: ListExportJobs :many
+SELECT * FROM export_jobs
+WHERE user_id=$1;
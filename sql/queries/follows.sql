@@ -0,0 +1,40 @@
+-- name: FollowUser :exec
+INSERT INTO follows(follower_id, followee_id, created_at)
+VALUES (
+	$1,
+	$2,
+	$3
+)
+ON CONFLICT (follower_id, followee_id) DO NOTHING;
+
+-- name: UnfollowUser :exec
+DELETE FROM follows
+WHERE follower_id=$1 AND followee_id=$2;
+
+-- name: ListFollowers :many
+SELECT sqlc.embed(users), follows.created_at AS followed_at
+FROM follows
+JOIN users ON users.id=follows.follower_id
+WHERE follows.followee_id=sqlc.arg('user_id')
+AND (sqlc.narg('after_followed_at')::timestamp IS NULL OR (follows.created_at, users.id) < (sqlc.narg('after_followed_at'), sqlc.narg('after_id')::uuid))
+ORDER BY follows.created_at DESC, users.id DESC
+LIMIT sqlc.arg('page_limit');
+
+-- name: ListFollowing :many
+SELECT sqlc.embed(users), follows.created_at AS followed_at
+FROM follows
+JOIN users ON users.id=follows.followee_id
+WHERE follows.follower_id=sqlc.arg('user_id')
+AND (sqlc.narg('after_followed_at')::timestamp IS NULL OR (follows.created_at, users.id) < (sqlc.narg('after_followed_at'), sqlc.narg('after_id')::uuid))
+ORDER BY follows.created_at DESC, users.id DESC
+LIMIT sqlc.arg('page_limit');
+
+-- name: GetFeed :many
+SELECT chirps.* FROM chirps
+JOIN follows ON follows.followee_id=chirps.user_id
+WHERE follows.follower_id=sqlc.arg('user_id')
+AND NOT chirps.is_tombstone AND chirps.deleted_at IS NULL AND chirps.publish_at IS NULL
+AND (sqlc.narg('after_created_at')::timestamp IS NULL OR (chirps.created_at, chirps.id) < (sqlc.narg('after_created_at'), sqlc.narg('after_id')::uuid))
+AND chirp_visible_to(chirps.visibility, chirps.user_id, sqlc.arg('user_id'))
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg('page_limit');
@@ -0,0 +1,44 @@
+-- name: UpsertHashtag :one
+INSERT INTO hashtags(tag, created_at)
+VALUES (
+	$1,
+	$2
+)
+ON CONFLICT (tag) DO UPDATE SET tag=EXCLUDED.tag
+RETURNING *;
+
+-- name: AddChirpHashtag :exec
+INSERT INTO chirp_hashtags(chirp_id, hashtag_id, created_at)
+VALUES (
+	$1,
+	$2,
+	$3
+)
+ON CONFLICT (chirp_id, hashtag_id) DO NOTHING;
+
+-- name: DeleteChirpHashtags :exec
+DELETE FROM chirp_hashtags
+WHERE chirp_id=$1;
+
+-- name: ListChirpsByHashtag :many
+SELECT chirps.* FROM chirps
+JOIN chirp_hashtags ON chirp_hashtags.chirp_id=chirps.id
+JOIN hashtags ON hashtags.id=chirp_hashtags.hashtag_id
+WHERE hashtags.tag=sqlc.arg('tag')
+AND NOT chirps.is_tombstone AND chirps.deleted_at IS NULL
+AND (sqlc.narg('after_created_at')::timestamp IS NULL OR (chirps.created_at, chirps.id) < (sqlc.narg('after_created_at'), sqlc.narg('after_id')::uuid))
+AND chirp_visible_to(chirps.visibility, chirps.user_id, sqlc.narg('viewer_id')::uuid)
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg('page_limit');
+
+-- name: GetTrendingHashtags :many
+SELECT hashtags.tag, COUNT(*) AS uses
+FROM chirp_hashtags
+JOIN hashtags ON hashtags.id=chirp_hashtags.hashtag_id
+JOIN chirps ON chirps.id=chirp_hashtags.chirp_id
+WHERE chirp_hashtags.created_at >= sqlc.arg('since')
+AND NOT chirps.is_tombstone AND chirps.deleted_at IS NULL AND chirps.publish_at IS NULL
+AND chirp_visible_to(chirps.visibility, chirps.user_id, sqlc.narg('viewer_id')::uuid)
+GROUP BY hashtags.tag
+ORDER BY uses DESC, hashtags.tag ASC
+LIMIT sqlc.arg('page_limit');
@@ -0,0 +1,43 @@
+-- name: CreateLink :one
+INSERT INTO links(created_at, code, url, chirp_id)
+VALUES (
+	$1,
+	$2,
+	$3,
+	$4
+)
+RETURNING *;
+
+-- name: GetLinkByCode :one
+SELECT links.* FROM links
+JOIN chirps ON chirps.id=links.chirp_id
+WHERE links.code=sqlc.arg('code')
+AND NOT chirps.is_tombstone AND chirps.deleted_at IS NULL AND chirps.publish_at IS NULL
+AND chirp_visible_to(chirps.visibility, chirps.user_id, sqlc.narg('viewer_id')::uuid)
+LIMIT 1;
+
+-- name: RecordLinkClick :exec
+INSERT INTO link_clicks(link_id, bucket, referrer, clicks)
+VALUES (
+	$1,
+	$2,
+	$3,
+	1
+)
+ON CONFLICT (link_id, bucket, referrer) DO UPDATE
+SET clicks=link_clicks.clicks + 1;
+
+-- name: ListChirpLinks :many
+SELECT * FROM links
+WHERE chirp_id=$1
+ORDER BY created_at ASC, code ASC;
+
+-- name: ListChirpLinkClicks :many
+SELECT link_clicks.* FROM link_clicks
+JOIN links ON links.id=link_clicks.link_id
+WHERE links.chirp_id=$1
+ORDER BY link_clicks.bucket ASC, link_clicks.referrer ASC;
+
+-- name: DeleteChirpLinks :exec
+DELETE FROM links
+WHERE chirp_id=$1;
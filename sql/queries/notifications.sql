@@ -0,0 +1,20 @@
+-- name: CreateNotification :exec
+-- Nothing is inserted when the user may not see the chirp, since the
+-- notification would point at a chirp they cannot open.
+INSERT INTO notifications(created_at, kind, user_id, actor_id, chirp_id)
+SELECT $1::timestamp, $2::text, $3::uuid, $4::uuid, chirps.id
+FROM chirps
+WHERE chirps.id=$5
+AND chirp_visible_to(chirps.visibility, chirps.user_id, $3::uuid);
+
+-- name: ListNotifications :many
+SELECT * FROM notifications
+WHERE user_id=sqlc.arg('user_id')
+AND (sqlc.narg('after_created_at')::timestamp IS NULL OR (created_at, id) < (sqlc.narg('after_created_at'), sqlc.narg('after_id')::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('page_limit');
+
+-- name: MarkNotificationsRead :exec
+UPDATE notifications
+SET read_at=$2
+WHERE user_id=$1 AND read_at IS NULL;
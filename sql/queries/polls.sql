@@ -0,0 +1,44 @@
+-- name: CreatePoll :exec
+INSERT INTO polls(chirp_id, closes_at)
+VALUES (
+	$1,
+	$2
+);
+
+-- name: AddPollOption :exec
+INSERT INTO poll_options(chirp_id, position, label)
+VALUES (
+	$1,
+	$2,
+	$3
+);
+
+-- name: GetPoll :one
+SELECT * FROM polls
+WHERE chirp_id=$1;
+
+-- name: GetPolls :many
+SELECT * FROM polls
+WHERE chirp_id=ANY(sqlc.arg('chirp_ids')::uuid[]);
+
+-- name: GetPollResults :many
+SELECT poll_options.*, COUNT(poll_votes.user_id) AS votes, COALESCE(BOOL_OR(poll_votes.user_id=sqlc.narg('viewer_id')::uuid), false)::boolean AS voted_by_me
+FROM poll_options
+LEFT JOIN poll_votes ON poll_votes.option_id=poll_options.id
+WHERE poll_options.chirp_id=ANY(sqlc.arg('chirp_ids')::uuid[])
+GROUP BY poll_options.id
+ORDER BY poll_options.chirp_id, poll_options.position;
+
+-- name: VotePoll :execrows
+INSERT INTO poll_votes(chirp_id, user_id, option_id, created_at)
+SELECT polls.chirp_id, sqlc.arg('user_id')::uuid, poll_options.id, sqlc.arg('created_at')::timestamp
+FROM polls
+JOIN poll_options ON poll_options.chirp_id=polls.chirp_id
+WHERE polls.chirp_id=sqlc.arg('chirp_id')
+AND poll_options.id=sqlc.arg('option_id')
+AND polls.closes_at > sqlc.arg('created_at')
+ON CONFLICT (chirp_id, user_id) DO NOTHING;
+
+-- name: DeleteChirpPoll :exec
+DELETE FROM polls
+WHERE chirp_id=$1;
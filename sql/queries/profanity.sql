@@ -0,0 +1,38 @@
+-- name: ListProfanityLists :many
+SELECT * FROM profanity_lists
+ORDER BY name;
+
+-- name: ListProfanityTerms :many
+SELECT * FROM profanity_terms
+ORDER BY list_id, term;
+
+-- name: GetProfanityList :one
+SELECT * FROM profanity_lists
+WHERE name=$1 LIMIT 1;
+
+-- name: CreateProfanityList :one
+INSERT INTO profanity_lists(created_at, name, locale, strategy)
+VALUES (
+	$1,
+	$2,
+	$3,
+	$4
+)
+RETURNING *;
+
+-- name: DeleteProfanityList :execrows
+DELETE FROM profanity_lists
+WHERE name=$1;
+
+-- name: AddProfanityTerm :exec
+INSERT INTO profanity_terms(list_id, term, created_at)
+VALUES (
+	$1,
+	$2,
+	$3
+)
+ON CONFLICT DO NOTHING;
+
+-- name: RemoveProfanityTerm :execrows
+DELETE FROM profanity_terms
+WHERE list_id=$1 AND term=$2;
@@ -30,3 +30,12 @@ UPDATE refresh_tokens
 SET revoked_at=$2, updated_at=$2
 WHERE token=$1;
 
+-- name: ListActiveSessions :many
+SELECT * FROM refresh_tokens
+WHERE user_id=$1 AND revoked_at IS NULL AND expires_at > $2
+ORDER BY created_at DESC;
+
+-- name: RevokeUserTokens :exec
+UPDATE refresh_tokens
+SET revoked_at=$2, updated_at=$2
+WHERE user_id=$1 AND revoked_at IS NULL;
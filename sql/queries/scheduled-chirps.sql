@@ -0,0 +1,48 @@
+-- name: ListScheduledChirps :many
+SELECT * FROM chirps
+WHERE user_id=sqlc.arg('user_id')
+AND publish_at IS NOT NULL
+AND deleted_at IS NULL
+AND (sqlc.narg('after_publish_at')::timestamp IS NULL OR (publish_at, id) > (sqlc.narg('after_publish_at'), sqlc.narg('after_id')::uuid))
+ORDER BY publish_at ASC, id ASC
+LIMIT sqlc.arg('page_limit');
+
+-- name: GetScheduledChirp :one
+SELECT * FROM chirps
+WHERE id=$1 AND publish_at IS NOT NULL AND deleted_at IS NULL LIMIT 1;
+
+-- name: RescheduleChirp :one
+-- Rescheduling also gives a chirp that failed to publish another try.
+UPDATE chirps
+SET publish_at=$2, updated_at=$3, publish_failed_at=NULL
+WHERE id=$1 AND publish_at IS NOT NULL
+RETURNING *;
+
+-- name: CancelScheduledChirp :execrows
+DELETE FROM chirps
+WHERE id=$1 AND publish_at IS NOT NULL;
+
+-- name: ListDueChirps :many
+SELECT * FROM chirps
+WHERE publish_at <= sqlc.arg('due_by')
+AND deleted_at IS NULL AND publish_failed_at IS NULL
+ORDER BY publish_at ASC, id ASC
+LIMIT sqlc.arg('page_limit');
+
+-- name: LockDueChirp :one
+-- Returns no row when the chirp is locked elsewhere or no longer due.
+SELECT * FROM chirps
+WHERE id=sqlc.arg('id') AND publish_at <= sqlc.arg('due_by')
+AND deleted_at IS NULL AND publish_failed_at IS NULL
+FOR UPDATE SKIP LOCKED;
+
+-- name: PublishChirp :one
+UPDATE chirps
+SET publish_at=NULL, created_at=sqlc.arg('published_at'), updated_at=sqlc.arg('published_at')
+WHERE id=sqlc.arg('id')
+RETURNING *;
+
+-- name: MarkChirpPublishFailed :exec
+UPDATE chirps
+SET publish_failed_at=sqlc.arg('failed_at')
+WHERE id=sqlc.arg('id') AND publish_at IS NOT NULL;
@@ -1,10 +1,11 @@
 -- name: CreateUser :one
-INSERT INTO users(created_at, updated_at, email, hashed_password)
+INSERT INTO users(created_at, updated_at, email, hashed_password, handle)
 VALUES (
 	$1,
 	$2,
 	$3,
-	$4
+	$4,
+	$5
 )
 RETURNING *;
 
@@ -26,3 +27,46 @@ UPDATE users
 SET is_chirpy_red=true
 WHERE id=$1
 RETURNING *;
+
+-- name: UpdateUserHandle :one
+UPDATE users
+SET handle=$2
+WHERE id=$1
+RETURNING *;
+
+-- name: GetUsersByHandles :many
+SELECT * FROM users
+WHERE handle=ANY(sqlc.arg('handles')::text[]);
+
+-- name: GetUserByID :one
+SELECT * FROM users
+WHERE id=$1 LIMIT 1;
+
+-- name: ScheduleUserDeletion :one
+UPDATE users
+SET delete_after=$2
+WHERE id=$1 AND delete_after IS NULL
+RETURNING *;
+
+-- name: CancelUserDeletion :one
+-- Only accounts still inside their grace period can be restored.
+UPDATE users
+SET delete_after=NULL
+WHERE id=sqlc.arg('id') AND delete_after > sqlc.arg('now')
+RETURNING *;
+
+-- name: ListDueUserDeletions :many
+SELECT * FROM users
+WHERE delete_after <= sqlc.arg('due_by')
+ORDER BY delete_after
+LIMIT sqlc.arg('page_limit');
+
+-- name: DeleteUser :exec
+DELETE FROM users
+WHERE id=$1;
+
+-- name: SetExpandSensitive :one
+UPDATE users
+SET expand_sensitive=$2
+WHERE id=$1
+RETURNING *;
@@ -0,0 +1,20 @@
+-- +goose Up
+ALTER TABLE chirps
+ADD COLUMN edited_at TIMESTAMP;
+
+CREATE TABLE chirp_revisions (
+	id	UUID PRIMARY KEY DEFAULT gen_random_uuid (),
+	created_at	TIMESTAMP	NOT NULL,
+	replaced_at	TIMESTAMP	NOT NULL,
+	body		TEXT		NOT NULL,
+	chirp_id	UUID		NOT NULL,
+	CONSTRAINT FK_chirp_id
+	FOREIGN KEY(chirp_id)	REFERENCES chirps(id)
+	ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE chirp_revisions;
+
+ALTER TABLE chirps
+DROP COLUMN edited_at;
@@ -0,0 +1,17 @@
+-- +goose Up
+ALTER TABLE chirps
+ADD COLUMN parent_chirp_id UUID,
+ADD COLUMN is_tombstone BOOLEAN NOT NULL DEFAULT false,
+ADD CONSTRAINT FK_parent_chirp_id
+FOREIGN KEY(parent_chirp_id)	REFERENCES chirps(id)
+ON DELETE SET NULL;
+
+CREATE INDEX chirps_parent_chirp_id_idx ON chirps(parent_chirp_id, created_at, id);
+
+-- +goose Down
+DROP INDEX chirps_parent_chirp_id_idx;
+
+ALTER TABLE chirps
+DROP CONSTRAINT FK_parent_chirp_id,
+DROP COLUMN is_tombstone,
+DROP COLUMN parent_chirp_id;
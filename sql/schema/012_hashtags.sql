@@ -0,0 +1,26 @@
+-- +goose Up
+CREATE TABLE hashtags (
+	id	UUID PRIMARY KEY DEFAULT gen_random_uuid (),
+	created_at	TIMESTAMP	NOT NULL,
+	tag		TEXT		NOT NULL,
+	UNIQUE(tag)
+);
+
+CREATE TABLE chirp_hashtags (
+	chirp_id	UUID		NOT NULL,
+	hashtag_id	UUID		NOT NULL,
+	created_at	TIMESTAMP	NOT NULL,
+	PRIMARY KEY(chirp_id, hashtag_id),
+	CONSTRAINT FK_chirp_id
+	FOREIGN KEY(chirp_id)	REFERENCES chirps(id)
+	ON DELETE CASCADE,
+	CONSTRAINT FK_hashtag_id
+	FOREIGN KEY(hashtag_id)	REFERENCES hashtags(id)
+	ON DELETE CASCADE
+);
+
+CREATE INDEX chirp_hashtags_hashtag_id_created_at_idx ON chirp_hashtags(hashtag_id, created_at);
+
+-- +goose Down
+DROP TABLE chirp_hashtags;
+DROP TABLE hashtags;
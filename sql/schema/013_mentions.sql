@@ -0,0 +1,45 @@
+-- +goose Up
+ALTER TABLE users
+ADD COLUMN handle TEXT UNIQUE;
+
+CREATE TABLE chirp_mentions (
+	chirp_id	UUID		NOT NULL,
+	user_id		UUID		NOT NULL,
+	start_offset	INTEGER		NOT NULL,
+	end_offset	INTEGER		NOT NULL,
+	PRIMARY KEY(chirp_id, start_offset),
+	CONSTRAINT FK_chirp_id
+	FOREIGN KEY(chirp_id)	REFERENCES chirps(id)
+	ON DELETE CASCADE,
+	CONSTRAINT FK_user_id
+	FOREIGN KEY(user_id)	REFERENCES users(id)
+	ON DELETE CASCADE
+);
+
+CREATE TABLE notifications (
+	id	UUID PRIMARY KEY DEFAULT gen_random_uuid (),
+	created_at	TIMESTAMP	NOT NULL,
+	read_at		TIMESTAMP,
+	kind		TEXT		NOT NULL,
+	user_id		UUID		NOT NULL,
+	actor_id	UUID		NOT NULL,
+	chirp_id	UUID		NOT NULL,
+	CONSTRAINT FK_user_id
+	FOREIGN KEY(user_id)	REFERENCES users(id)
+	ON DELETE CASCADE,
+	CONSTRAINT FK_actor_id
+	FOREIGN KEY(actor_id)	REFERENCES users(id)
+	ON DELETE CASCADE,
+	CONSTRAINT FK_chirp_id
+	FOREIGN KEY(chirp_id)	REFERENCES chirps(id)
+	ON DELETE CASCADE
+);
+
+CREATE INDEX notifications_user_id_created_at_idx ON notifications(user_id, created_at, id);
+
+-- +goose Down
+DROP TABLE notifications;
+DROP TABLE chirp_mentions;
+
+ALTER TABLE users
+DROP COLUMN handle;
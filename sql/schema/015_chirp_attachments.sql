@@ -0,0 +1,17 @@
+-- +goose Up
+CREATE TABLE chirp_attachments (
+	id	UUID PRIMARY KEY DEFAULT gen_random_uuid (),
+	created_at	TIMESTAMP	NOT NULL,
+	chirp_id	UUID		NOT NULL,
+	position	INTEGER		NOT NULL,
+	content_type	TEXT		NOT NULL,
+	width		INTEGER		NOT NULL,
+	height		INTEGER		NOT NULL,
+	UNIQUE(chirp_id, position),
+	CONSTRAINT FK_chirp_id
+	FOREIGN KEY(chirp_id)	REFERENCES chirps(id)
+	ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE chirp_attachments;
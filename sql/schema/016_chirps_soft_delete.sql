@@ -0,0 +1,23 @@
+-- +goose Up
+ALTER TABLE chirps
+ADD COLUMN deleted_at TIMESTAMP;
+
+CREATE INDEX chirps_deleted_at_idx ON chirps(deleted_at)
+WHERE deleted_at IS NOT NULL;
+
+-- a deleted rechirp should not stop the user from rechirping again
+DROP INDEX chirps_user_id_rechirp_of_id_idx;
+CREATE UNIQUE INDEX chirps_user_id_rechirp_of_id_idx ON chirps(user_id, rechirp_of_id)
+WHERE rechirp_of_id IS NOT NULL AND deleted_at IS NULL;
+
+-- +goose Down
+DROP INDEX chirps_user_id_rechirp_of_id_idx;
+DELETE FROM chirps
+WHERE deleted_at IS NOT NULL;
+CREATE UNIQUE INDEX chirps_user_id_rechirp_of_id_idx ON chirps(user_id, rechirp_of_id)
+WHERE rechirp_of_id IS NOT NULL;
+
+DROP INDEX chirps_deleted_at_idx;
+
+ALTER TABLE chirps
+DROP COLUMN deleted_at;
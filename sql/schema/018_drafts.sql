@@ -0,0 +1,16 @@
+-- +goose Up
+CREATE TABLE drafts (
+	id	UUID PRIMARY KEY DEFAULT gen_random_uuid (),
+	created_at	TIMESTAMP	NOT NULL,
+	updated_at	TIMESTAMP	NOT NULL,
+	body		TEXT		NOT NULL,
+	user_id		UUID		NOT NULL,
+	CONSTRAINT FK_user_id
+	FOREIGN KEY(user_id)	REFERENCES users(id)
+	ON DELETE CASCADE
+);
+
+CREATE INDEX drafts_user_id_updated_at_id_idx ON drafts(user_id, updated_at, id);
+
+-- +goose Down
+DROP TABLE drafts;
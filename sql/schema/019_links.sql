@@ -0,0 +1,28 @@
+-- +goose Up
+CREATE TABLE links (
+	id	UUID PRIMARY KEY DEFAULT gen_random_uuid (),
+	created_at	TIMESTAMP	NOT NULL,
+	code		TEXT		NOT NULL UNIQUE,
+	url		TEXT		NOT NULL,
+	chirp_id	UUID		NOT NULL,
+	CONSTRAINT FK_chirp_id
+	FOREIGN KEY(chirp_id)	REFERENCES chirps(id)
+	ON DELETE CASCADE
+);
+
+CREATE INDEX links_chirp_id_idx ON links(chirp_id);
+
+CREATE TABLE link_clicks (
+	link_id		UUID		NOT NULL,
+	bucket		TIMESTAMP	NOT NULL,
+	referrer	TEXT		NOT NULL,
+	clicks		BIGINT		NOT NULL,
+	PRIMARY KEY(link_id, bucket, referrer),
+	CONSTRAINT FK_link_id
+	FOREIGN KEY(link_id)	REFERENCES links(id)
+	ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE link_clicks;
+DROP TABLE links;
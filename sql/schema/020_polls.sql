@@ -0,0 +1,43 @@
+-- +goose Up
+CREATE TABLE polls (
+	chirp_id	UUID		PRIMARY KEY,
+	closes_at	TIMESTAMP	NOT NULL,
+	CONSTRAINT FK_chirp_id
+	FOREIGN KEY(chirp_id)	REFERENCES chirps(id)
+	ON DELETE CASCADE
+);
+
+CREATE TABLE poll_options (
+	id	UUID PRIMARY KEY DEFAULT gen_random_uuid (),
+	chirp_id	UUID		NOT NULL,
+	position	INTEGER		NOT NULL,
+	label		TEXT		NOT NULL,
+	UNIQUE(chirp_id, position),
+	CONSTRAINT FK_chirp_id
+	FOREIGN KEY(chirp_id)	REFERENCES polls(chirp_id)
+	ON DELETE CASCADE
+);
+
+CREATE TABLE poll_votes (
+	chirp_id	UUID		NOT NULL,
+	user_id		UUID		NOT NULL,
+	option_id	UUID		NOT NULL,
+	created_at	TIMESTAMP	NOT NULL,
+	PRIMARY KEY(chirp_id, user_id),
+	CONSTRAINT FK_chirp_id
+	FOREIGN KEY(chirp_id)	REFERENCES polls(chirp_id)
+	ON DELETE CASCADE,
+	CONSTRAINT FK_user_id
+	FOREIGN KEY(user_id)	REFERENCES users(id)
+	ON DELETE CASCADE,
+	CONSTRAINT FK_option_id
+	FOREIGN KEY(option_id)	REFERENCES poll_options(id)
+	ON DELETE CASCADE
+);
+
+CREATE INDEX poll_votes_option_id_idx ON poll_votes(option_id);
+
+-- +goose Down
+DROP TABLE poll_votes;
+DROP TABLE poll_options;
+DROP TABLE polls;
@@ -0,0 +1,18 @@
+-- +goose Up
+CREATE TABLE bookmarks (
+	user_id		UUID		NOT NULL,
+	chirp_id	UUID		NOT NULL,
+	created_at	TIMESTAMP	NOT NULL,
+	PRIMARY KEY(user_id, chirp_id),
+	CONSTRAINT FK_user_id
+	FOREIGN KEY(user_id)	REFERENCES users(id)
+	ON DELETE CASCADE,
+	CONSTRAINT FK_chirp_id
+	FOREIGN KEY(chirp_id)	REFERENCES chirps(id)
+	ON DELETE CASCADE
+);
+
+CREATE INDEX bookmarks_user_id_created_at_idx ON bookmarks(user_id, created_at);
+
+-- +goose Down
+DROP TABLE bookmarks;
@@ -0,0 +1,20 @@
+-- +goose Up
+CREATE TABLE export_jobs (
+	id	UUID PRIMARY KEY DEFAULT gen_random_uuid (),
+	created_at	TIMESTAMP	NOT NULL,
+	user_id		UUID		NOT NULL,
+	status		TEXT		NOT NULL DEFAULT 'pending'
+	CHECK (status IN ('pending', 'running', 'ready', 'failed')),
+	started_at	TIMESTAMP,
+	finished_at	TIMESTAMP,
+	expires_at	TIMESTAMP,
+	CONSTRAINT FK_user_id
+	FOREIGN KEY(user_id)	REFERENCES users(id)
+	ON DELETE CASCADE
+);
+
+CREATE INDEX export_jobs_user_id_created_at_idx ON export_jobs(user_id, created_at);
+CREATE INDEX export_jobs_status_idx ON export_jobs(status) WHERE status IN ('pending', 'running');
+
+-- +goose Down
+DROP TABLE export_jobs;
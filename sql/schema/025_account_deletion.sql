@@ -0,0 +1,12 @@
+-- +goose Up
+ALTER TABLE users
+ADD COLUMN delete_after TIMESTAMP;
+
+CREATE INDEX users_delete_after_idx ON users(delete_after)
+WHERE delete_after IS NOT NULL;
+
+-- +goose Down
+DROP INDEX users_delete_after_idx;
+
+ALTER TABLE users
+DROP COLUMN delete_after;
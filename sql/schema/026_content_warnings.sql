@@ -0,0 +1,17 @@
+-- +goose Up
+ALTER TABLE chirps
+ADD COLUMN content_warning TEXT,
+ADD COLUMN sensitive BOOLEAN NOT NULL DEFAULT false;
+
+ALTER TABLE users
+ADD COLUMN is_moderator BOOLEAN NOT NULL DEFAULT false,
+ADD COLUMN expand_sensitive BOOLEAN NOT NULL DEFAULT false;
+
+-- +goose Down
+ALTER TABLE users
+DROP COLUMN expand_sensitive,
+DROP COLUMN is_moderator;
+
+ALTER TABLE chirps
+DROP COLUMN sensitive,
+DROP COLUMN content_warning;
@@ -0,0 +1,33 @@
+-- +goose Up
+CREATE TABLE profanity_lists (
+	id		UUID PRIMARY KEY DEFAULT gen_random_uuid (),
+	created_at	TIMESTAMP	NOT NULL,
+	name		TEXT		NOT NULL UNIQUE,
+	locale		TEXT		NOT NULL,
+	strategy	TEXT		NOT NULL
+	CHECK (strategy IN ('mask', 'reject', 'flag'))
+);
+
+CREATE TABLE profanity_terms (
+	list_id		UUID		NOT NULL,
+	term		TEXT		NOT NULL,
+	created_at	TIMESTAMP	NOT NULL,
+	PRIMARY KEY(list_id, term),
+	CONSTRAINT FK_list_id
+	FOREIGN KEY(list_id)	REFERENCES profanity_lists(id)
+	ON DELETE CASCADE
+);
+
+-- the words that used to be hard-coded
+WITH list AS (
+	INSERT INTO profanity_lists(created_at, name, locale, strategy)
+	VALUES (NOW(), 'default', 'en', 'mask')
+	RETURNING id
+)
+INSERT INTO profanity_terms(list_id, term, created_at)
+SELECT list.id, term, NOW()
+FROM list, unnest(ARRAY['kerfuffle', 'sharbert', 'fornax']) AS term;
+
+-- +goose Down
+DROP TABLE profanity_terms;
+DROP TABLE profanity_lists;
@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+-- chirp_visible_to is the one place that decides who may see a chirp.
+-- A NULL viewer is an anonymous reader.
+CREATE FUNCTION chirp_visible_to(chirp_visibility TEXT, author_id UUID, viewer_id UUID)
+RETURNS BOOLEAN
+LANGUAGE SQL STABLE
+AS $$
+	SELECT chirp_visibility='public'
+	OR COALESCE(author_id=viewer_id, false)
+	OR (chirp_visibility='followers' AND EXISTS (
+		SELECT 1 FROM follows
+		WHERE follows.follower_id=viewer_id AND follows.followee_id=author_id
+	))
+$$;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP FUNCTION chirp_visible_to(TEXT, UUID, UUID);
@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE chirps
+ADD COLUMN publish_failed_at TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE chirps
+DROP COLUMN publish_failed_at;
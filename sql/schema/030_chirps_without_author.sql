@@ -0,0 +1,11 @@
+-- +goose Up
+-- Tombstones of a deleted account stay behind without an author.
+ALTER TABLE chirps
+ALTER COLUMN user_id DROP NOT NULL;
+
+-- +goose Down
+DELETE FROM chirps
+WHERE user_id IS NULL;
+
+ALTER TABLE chirps
+ALTER COLUMN user_id SET NOT NULL;
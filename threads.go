@@ -0,0 +1,232 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/uncomfyhalomacro/chirpy/internal/database"
+	"github.com/uncomfyhalomacro/chirpy/internal/pagination"
+	"github.com/uncomfyhalomacro/chirpy/internal/thread"
+	"log"
+	"net/http"
+)
+
+const (
+	// How many levels of replies are nested below each direct reply.
+	threadMaxDepth = 8
+	// Upper bound on the nested replies loaded for a single page.
+	threadMaxRows = 500
+)
+
+type threadNode struct {
+	returnValidChirp
+	Replies []threadNode `json:"replies"`
+}
+
+type returnThread struct {
+	Ancestors  []returnValidChirp `json:"ancestors"`
+	Chirp      returnValidChirp   `json:"chirp"`
+	Replies    []threadNode       `json:"replies"`
+	NextCursor string             `json:"next_cursor,omitempty"`
+}
+
+// tombstoneChirp blanks a chirp in place instead of deleting its row.
+func (cfg *apiConfig) tombstoneChirp(ctx context.Context, id uuid.UUID) error {
+	tx, err := cfg.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+	if err = qtx.DeleteChirpRevisions(ctx, id); err != nil {
+		return err
+	}
+	if err = qtx.DeleteChirpHashtags(ctx, id); err != nil {
+		return err
+	}
+	if err = qtx.DeleteChirpMentions(ctx, id); err != nil {
+		return err
+	}
+	// the links, poll and likes would otherwise stay reachable through
+	// /l/{code}, poll results and like counts
+	if err = qtx.DeleteChirpLinks(ctx, id); err != nil {
+		return err
+	}
+	if err = qtx.DeleteChirpPoll(ctx, id); err != nil {
+		return err
+	}
+	if err = qtx.DeleteChirpLikes(ctx, id); err != nil {
+		return err
+	}
+	attachments, err := qtx.DeleteChirpAttachments(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err = qtx.TombstoneChirp(ctx, id); err != nil {
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+	cfg.removeAttachmentFiles(attachments)
+	return nil
+}
+
+// tombstoneToJSON shows a chirp that is gone but still holds its thread
+// together. See thread.Hidden.
+func tombstoneToJSON(chirp database.Chirp) returnValidChirp {
+	respBody := returnValidChirp{
+		ID:          chirp.ID,
+		CreatedAt:   chirp.CreatedAt,
+		UpdatedAt:   chirp.UpdatedAt,
+		Tombstone:   true,
+		Visibility:  chirp.Visibility,
+		Mentions:    []returnMention{},
+		Attachments: []returnAttachment{},
+	}
+	if chirp.UserID.Valid {
+		respBody.UserID = &chirp.UserID.UUID
+	}
+	if chirp.ParentChirpID.Valid {
+		respBody.ReplyTo = &chirp.ParentChirpID.UUID
+	}
+	return respBody
+}
+
+func threadNodesToJSON(nodes []thread.Node, respBodies map[uuid.UUID]returnValidChirp) []threadNode {
+	respNodes := make([]threadNode, 0, len(nodes))
+	for _, node := range nodes {
+		respNodes = append(respNodes, threadNode{
+			returnValidChirp: respBodies[node.Chirp.ID],
+			Replies:          threadNodesToJSON(node.Replies, respBodies),
+		})
+	}
+	return respNodes
+}
+
+func (cfg *apiConfig) getChirpThread(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		msg := fmt.Sprintf("400 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 400)
+		return
+	}
+	limit, err := pagination.ParseLimit(r.URL.Query().Get("limit"))
+	if err != nil {
+		msg := fmt.Sprintf("400 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 400)
+		return
+	}
+	viewerID := cfg.viewerID(r)
+	params := database.ListChirpRepliesParams{
+		ParentID:  id,
+		ViewerID:  viewerID,
+		PageLimit: limit + 1,
+	}
+	if rawCursor := r.URL.Query().Get("cursor"); rawCursor != "" {
+		cursor, err := pagination.DecodeCursor(rawCursor)
+		if err != nil {
+			msg := fmt.Sprintf("400 - %s", err)
+			log.Printf("%s\n", msg)
+			http.Error(w, msg, 400)
+			return
+		}
+		params.AfterCreatedAt = sql.NullTime{Time: cursor.Time, Valid: true}
+		params.AfterID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
+	}
+	chirp, err := cfg.db.GetChirp(r.Context(), database.GetChirpParams{
+		ID:       id,
+		ViewerID: viewerID,
+	})
+	if err != nil {
+		msg := fmt.Sprintf("404 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 404)
+		return
+	}
+	ancestors, err := cfg.db.GetChirpAncestors(r.Context(), database.GetChirpAncestorsParams{
+		ID:       chirp.ID,
+		ViewerID: viewerID,
+	})
+	if err != nil {
+		msg := fmt.Sprintf("500 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	replies, err := cfg.db.ListChirpReplies(r.Context(), params)
+	if err != nil {
+		msg := fmt.Sprintf("500 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	var respBody returnThread
+	if len(replies) > int(limit) {
+		replies = replies[:limit]
+		last := replies[len(replies)-1]
+		respBody.NextCursor = pagination.Cursor{Time: last.CreatedAt, ID: last.ID}.Encode()
+	}
+
+	var descendants []database.Chirp
+	if len(replies) > 0 {
+		rootIDs := make([]uuid.UUID, 0, len(replies))
+		for _, reply := range replies {
+			rootIDs = append(rootIDs, reply.ID)
+		}
+		descendants, err = cfg.db.GetChirpDescendants(r.Context(), database.GetChirpDescendantsParams{
+			RootIds:  rootIDs,
+			MaxDepth: threadMaxDepth,
+			ViewerID: viewerID,
+			MaxRows:  threadMaxRows,
+		})
+		if err != nil {
+			msg := fmt.Sprintf("500 - %s", err)
+			log.Printf("%s\n", msg)
+			http.Error(w, msg, 500)
+			return
+		}
+	}
+
+	nested := append(append([]database.Chirp{}, replies...), descendants...)
+	respBodies := map[uuid.UUID]returnValidChirp{}
+	visible := []database.Chirp{chirp}
+	for _, other := range append(append([]database.Chirp{}, ancestors...), nested...) {
+		if thread.Hidden(other) {
+			respBodies[other.ID] = tombstoneToJSON(other)
+		} else {
+			visible = append(visible, other)
+		}
+	}
+	loaded, err := cfg.loadChirpsJSON(r.Context(), viewerID, visible)
+	if err != nil {
+		msg := fmt.Sprintf("500 - %s", err)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	for _, loadedChirp := range loaded {
+		respBodies[loadedChirp.ID] = loadedChirp
+	}
+	respBody.Chirp = respBodies[chirp.ID]
+	respBody.Ancestors = []returnValidChirp{}
+	for _, ancestor := range ancestors {
+		respBody.Ancestors = append(respBody.Ancestors, respBodies[ancestor.ID])
+	}
+	respBody.Replies = threadNodesToJSON(thread.Build(chirp.ID, nested), respBodies)
+
+	dat, errMarshal := json.Marshal(respBody)
+	if errMarshal != nil {
+		msg := fmt.Sprintf("500 - %s", errMarshal)
+		log.Printf("%s\n", msg)
+		http.Error(w, msg, 500)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+	w.Write(dat)
+}
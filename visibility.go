@@ -0,0 +1,26 @@
+package main
+
+import (
+	"fmt"
+)
+
+// A chirp's visibility decides who can see it. The check itself lives in
+// the SQL of every query that reads chirps, so a chirp the viewer may not
+// see is simply not found.
+const (
+	visibilityPublic    = "public"
+	visibilityFollowers = "followers"
+	visibilityPrivate   = "private"
+)
+
+// parseVisibility checks a visibility from a request. Chirps are public
+// unless asked otherwise.
+func parseVisibility(visibility string) (string, error) {
+	switch visibility {
+	case "":
+		return visibilityPublic, nil
+	case visibilityPublic, visibilityFollowers, visibilityPrivate:
+		return visibility, nil
+	}
+	return "", fmt.Errorf("visibility should be one of %s, %s or %s", visibilityPublic, visibilityFollowers, visibilityPrivate)
+}